@@ -2,16 +2,25 @@ package ollama
 
 // ChatRequestBuilder represents the chat API request.
 type ChatRequestBuilder struct {
-	Model     *string   `json:"model"`
-	Format    *string   `json:"format"`
-	Raw       *bool     `json:"raw"`
-	Messages  []Message `json:"messages"`
-	KeepAlive *string   `json:"keep_alive,omitempty"`
-	Options   *Options  `json:"options"`
+	Model     *string         `json:"model"`
+	Format    *ResponseFormat `json:"format"`
+	Raw       *bool           `json:"raw"`
+	Messages  []Message       `json:"messages"`
+	KeepAlive *string         `json:"keep_alive,omitempty"`
+	Options   *Options        `json:"options"`
+	Tools     []Tool          `json:"tools,omitempty"`
+	Think     *bool           `json:"think,omitempty"`
 
-	Stream           *bool                            `json:"stream"`
-	StreamBufferSize *int                             `json:"-"`
-	StreamFunc       func(r *ChatResponse, err error) `json:"-"`
+	HistoryPolicy *HistoryPolicy `json:"-"`
+	SummaryPolicy *SummaryPolicy `json:"-"`
+
+	ValidationRetry *ValidationRetry `json:"-"`
+	KeepThinking    *bool            `json:"-"`
+
+	Stream           *bool                                  `json:"stream"`
+	StreamBufferSize *int                                   `json:"-"`
+	StreamFunc       func(r *ChatResponse, err error)       `json:"-"`
+	StreamHandler    func(r *ChatResponse, err error) error `json:"-"`
 }
 
 // WithModel sets the model used for this request.
@@ -38,13 +47,39 @@ func (f *ChatFunc) WithStream(v bool, bufferSize int, fn func(r *ChatResponse, e
 	}
 }
 
+// WithStreamHandler passes a function to read the stream that can stop it early.
+// If the handler returns ErrStopStream, the connection is closed and the response received so far is returned
+// without an error. Any other error stops the stream and is returned.
+//
+// Parameters:
+//   - bufferSize: The size of the streamed buffer
+//   - fn: The function to handle streaming types.
+func (f *ChatFunc) WithStreamHandler(bufferSize int, fn func(r *ChatResponse, err error) error) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.Stream = pointer(true)
+		r.StreamBufferSize = &bufferSize
+		r.StreamHandler = fn
+	}
+}
+
 // WithFormat sets the format to return a response in. Currently, the only accepted value is "json".
+// Use WithFormatSchema to constrain the response to a JSON schema.
 //
 // Parameters:
 //   - v: The format string.
 func (f *ChatFunc) WithFormat(v string) func(*ChatRequestBuilder) {
 	return func(r *ChatRequestBuilder) {
-		r.Format = &v
+		r.Format = &ResponseFormat{Type: v}
+	}
+}
+
+// WithFormatSchema constrains the response to a JSON schema. See also ChatInto.
+//
+// Parameters:
+//   - v: The JSON schema.
+func (f *ChatFunc) WithFormatSchema(v JSONSchema) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.Format = &ResponseFormat{Schema: &v}
 	}
 }
 
@@ -119,3 +154,90 @@ func (f *ChatFunc) WithOptions(v Options) func(*ChatRequestBuilder) {
 		r.Options = &v
 	}
 }
+
+// WithHistoryPolicy sets the policy that controls which messages of the chat history are sent with this request.
+// It overrides the history policy of the chat.
+//
+// Parameters:
+//   - v: The history policy.
+func (f *ChatFunc) WithHistoryPolicy(v HistoryPolicy) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.HistoryPolicy = &v
+	}
+}
+
+// WithSummaryPolicy sets the policy that condenses the older messages of the chat into a rolling summary.
+// It overrides the summary policy of the chat.
+//
+// Parameters:
+//   - v: The summary policy.
+func (f *ChatFunc) WithSummaryPolicy(v SummaryPolicy) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.SummaryPolicy = &v
+	}
+}
+
+// WithTools appends tools that the model may call. The calls are returned in ChatResponse.Message.ToolCalls
+// and their results are sent back in messages with the tool role, see NewToolMessage.
+//
+// Parameters:
+//   - v: The tools to append.
+func (f *ChatFunc) WithTools(v ...Tool) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.Tools = append(r.Tools, v...)
+	}
+}
+
+// WithToolRegistry appends the tools of the registry that the model may call.
+//
+// Parameters:
+//   - v: The tool registry.
+func (f *ChatFunc) WithToolRegistry(v *ToolRegistry) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.Tools = append(r.Tools, v.Tools()...)
+	}
+}
+
+// WithToolResult appends a message with the result of a tool call.
+//
+// Parameters:
+//   - name: The name of the called function.
+//   - content: The result of the call.
+func (f *ChatFunc) WithToolResult(name, content string) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.Messages = append(r.Messages, NewToolMessage(name, content))
+	}
+}
+
+// WithValidationRetry retries the structured requests of ChatInto when the response does not conform to the expected type.
+// It has no effect on other requests.
+//
+// Parameters:
+//   - v: The retry policy.
+func (f *ChatFunc) WithValidationRetry(v ValidationRetry) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.ValidationRetry = &v
+	}
+}
+
+// WithThink enables or disables the thinking of reasoning models. The thinking is returned in Message.Thinking.
+// If the model inlines its thinking in <think> blocks instead, they are moved to Message.Thinking, unless thinking is disabled.
+// Such models usually reject this option, so it should not be set for them.
+//
+// Parameters:
+//   - v: A boolean indicating whether the model should think before answering.
+func (f *ChatFunc) WithThink(v bool) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.Think = &v
+	}
+}
+
+// WithKeepThinking sets whether the thinking of the reply is stored in the chat. It overrides Chat.KeepThinking.
+//
+// Parameters:
+//   - v: A boolean indicating whether to store the thinking.
+func (f *ChatFunc) WithKeepThinking(v bool) func(*ChatRequestBuilder) {
+	return func(r *ChatRequestBuilder) {
+		r.KeepThinking = &v
+	}
+}
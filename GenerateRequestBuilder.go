@@ -2,20 +2,24 @@ package ollama
 
 // GenerateRequestBuilder represents the generate API request.
 type GenerateRequestBuilder struct {
-	Model     *string  `json:"model"`
-	Prompt    *string  `json:"prompt"`
-	System    *string  `json:"system"`
-	Template  *string  `json:"template"`
-	Format    *string  `json:"format"`
-	Images    []string `json:"images"`
-	Raw       *bool    `json:"raw"`
-	Context   []int    `json:"context,omitempty"`
-	KeepAlive *string  `json:"keep_alive,omitempty"`
-	Options   *Options `json:"options"`
-
-	Stream           *bool                                `json:"stream"`
-	StreamBufferSize *int                                 `json:"-"`
-	StreamFunc       func(r *GenerateResponse, err error) `json:"-"`
+	Model     *string         `json:"model"`
+	Prompt    *string         `json:"prompt"`
+	System    *string         `json:"system"`
+	Template  *string         `json:"template"`
+	Format    *ResponseFormat `json:"format"`
+	Images    []string        `json:"images"`
+	Raw       *bool           `json:"raw"`
+	Context   []int           `json:"context,omitempty"`
+	KeepAlive *string         `json:"keep_alive,omitempty"`
+	Options   *Options        `json:"options"`
+	Think     *bool           `json:"think,omitempty"`
+
+	ValidationRetry *ValidationRetry `json:"-"`
+
+	Stream           *bool                                      `json:"stream"`
+	StreamBufferSize *int                                       `json:"-"`
+	StreamFunc       func(r *GenerateResponse, err error)       `json:"-"`
+	StreamHandler    func(r *GenerateResponse, err error) error `json:"-"`
 }
 
 // WithModel sets the model used for this request.
@@ -92,13 +96,39 @@ func (c GenerateFunc) WithStream(v bool, bufferSize int, f func(r *GenerateRespo
 	}
 }
 
+// WithStreamHandler passes a function to read the stream that can stop it early.
+// If the handler returns ErrStopStream, the connection is closed and the response received so far is returned
+// without an error. Any other error stops the stream and is returned.
+//
+// Parameters:
+//   - bufferSize: The size of the streamed buffer
+//   - f: The function to handle streaming types.
+func (c GenerateFunc) WithStreamHandler(bufferSize int, f func(r *GenerateResponse, err error) error) func(*GenerateRequestBuilder) {
+	return func(r *GenerateRequestBuilder) {
+		r.Stream = pointer(true)
+		r.StreamBufferSize = &bufferSize
+		r.StreamHandler = f
+	}
+}
+
 // WithFormat sets the format to return a response in. Currently, the only accepted value is "json".
+// Use WithFormatSchema to constrain the response to a JSON schema.
 //
 // Parameters:
 //   - v: The format string.
 func (c GenerateFunc) WithFormat(v string) func(*GenerateRequestBuilder) {
 	return func(r *GenerateRequestBuilder) {
-		r.Format = &v
+		r.Format = &ResponseFormat{Type: v}
+	}
+}
+
+// WithFormatSchema constrains the response to a JSON schema. See also GenerateInto.
+//
+// Parameters:
+//   - v: The JSON schema.
+func (c GenerateFunc) WithFormatSchema(v JSONSchema) func(*GenerateRequestBuilder) {
+	return func(r *GenerateRequestBuilder) {
+		r.Format = &ResponseFormat{Schema: &v}
 	}
 }
 
@@ -159,3 +189,26 @@ func (c GenerateFunc) WithOptions(v Options) func(*GenerateRequestBuilder) {
 		r.Options = &v
 	}
 }
+
+// WithValidationRetry retries the structured requests of GenerateInto when the response does not conform to the expected type.
+// It has no effect on other requests.
+//
+// Parameters:
+//   - v: The retry policy.
+func (c GenerateFunc) WithValidationRetry(v ValidationRetry) func(*GenerateRequestBuilder) {
+	return func(r *GenerateRequestBuilder) {
+		r.ValidationRetry = &v
+	}
+}
+
+// WithThink enables or disables the thinking of reasoning models. The thinking is returned in GenerateResponse.Thinking.
+// If the model inlines its thinking in <think> blocks instead, they are moved to GenerateResponse.Thinking, unless thinking is disabled.
+// Such models usually reject this option, so it should not be set for them.
+//
+// Parameters:
+//   - v: A boolean indicating whether the model should think before answering.
+func (c GenerateFunc) WithThink(v bool) func(*GenerateRequestBuilder) {
+	return func(r *GenerateRequestBuilder) {
+		r.Think = &v
+	}
+}
@@ -7,9 +7,10 @@ type ModelFileRequestBuilder struct {
 	Modelfile *string `json:"modelfile"`
 	Quantize  *string `json:"quantize"`
 
-	Stream           *bool                              `json:"stream"`
-	StreamBufferSize *int                               `json:"-"`
-	StreamFunc       func(r *StatusResponse, err error) `json:"-"`
+	Stream           *bool                                    `json:"stream"`
+	StreamBufferSize *int                                     `json:"-"`
+	StreamFunc       func(r *StatusResponse, err error)       `json:"-"`
+	StreamHandler    func(r *StatusResponse, err error) error `json:"-"`
 
 	from       *string
 	parameters []Parameter
@@ -61,6 +62,21 @@ func (f *CreateModelFunc) WithStream(v bool, bufferSize int, fc func(r *StatusRe
 	}
 }
 
+// WithStreamHandler passes a function to read the stream that can stop it early.
+// If the handler returns ErrStopStream, the connection is closed and the response received so far is returned
+// without an error. Any other error stops the stream and is returned.
+//
+// Parameters:
+//   - bufferSize: The size of the streamed buffer
+//   - fc: The function to handle streaming types.
+func (f *CreateModelFunc) WithStreamHandler(bufferSize int, fc func(r *StatusResponse, err error) error) func(*ModelFileRequestBuilder) {
+	return func(r *ModelFileRequestBuilder) {
+		r.Stream = pointer(true)
+		r.StreamBufferSize = &bufferSize
+		r.StreamHandler = fc
+	}
+}
+
 // WithQuantize sets the quantize for this request.
 //
 // Parameters:
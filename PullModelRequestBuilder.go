@@ -7,9 +7,10 @@ type PullModelRequestBuilder struct {
 	Username *string `json:"username"`
 	Password *string `json:"password"`
 
-	Stream           *bool                                     `json:"stream"`
-	StreamBufferSize *int                                      `json:"-"`
-	StreamFunc       func(r *PushPullModelResponse, err error) `json:"-"`
+	Stream           *bool                                           `json:"stream"`
+	StreamBufferSize *int                                            `json:"-"`
+	StreamFunc       func(r *PushPullModelResponse, err error)       `json:"-"`
+	StreamHandler    func(r *PushPullModelResponse, err error) error `json:"-"`
 }
 
 // WithModel sets the model used for this request.
@@ -65,3 +66,18 @@ func (f *PullModelFunc) WithStream(v bool, bufferSize int, fc func(r *PushPullMo
 		r.StreamFunc = fc
 	}
 }
+
+// WithStreamHandler passes a function to read the stream that can stop it early.
+// If the handler returns ErrStopStream, the connection is closed and the response received so far is returned
+// without an error. Any other error stops the stream and is returned.
+//
+// Parameters:
+//   - bufferSize: The size of the streamed buffer
+//   - fc: The function to handle streaming types.
+func (f *PullModelFunc) WithStreamHandler(bufferSize int, fc func(r *PushPullModelResponse, err error) error) func(*PullModelRequestBuilder) {
+	return func(r *PullModelRequestBuilder) {
+		r.Stream = pointer(true)
+		r.StreamBufferSize = &bufferSize
+		r.StreamHandler = fc
+	}
+}
@@ -0,0 +1,201 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AgentOptions configures RunAgent.
+type AgentOptions struct {
+	// Tools are the tools the model may call. They are sent with every request.
+	Tools *ToolRegistry
+
+	// MaxIterations is the maximum number of chat requests. Defaults to 10.
+	MaxIterations int
+
+	// Timeout is the time budget of the whole run, including the tool calls. Zero means no limit.
+	Timeout time.Duration
+
+	// Parallel executes the tool calls of a reply concurrently.
+	Parallel bool
+
+	// Approve is called before each tool call. If it returns false, the tool is not called
+	// and the model is told that the call was denied. If nil, all calls are approved.
+	Approve func(ctx context.Context, call ToolCall) bool
+
+	// OnStep is called after each iteration, once its tool calls have been executed.
+	OnStep func(step AgentStep)
+}
+
+// AgentStep is the trace of an iteration of RunAgent.
+type AgentStep struct {
+	Iteration int              // The index of the iteration, starting at zero.
+	Messages  []Message        // The new messages sent with the request, such as the prompt or the tool results.
+	Response  *ChatResponse    // The reply of the model.
+	ToolCalls []ToolCallResult // The tool calls requested in the reply, in order.
+	Duration  time.Duration    // The duration of the iteration, including the tool calls.
+}
+
+// ToolCallResult is the trace of a tool call executed by RunAgent.
+type ToolCallResult struct {
+	Call     ToolCall      // The call requested by the model.
+	Approved bool          // Whether the call was approved.
+	Result   Message       // The message sent back to the model.
+	Err      error         // The error of the call, which was reported to the model.
+	Duration time.Duration // The duration of the call.
+}
+
+// AgentResult is the result of RunAgent.
+type AgentResult struct {
+	Response *ChatResponse // The last reply of the model, the final answer if the run completed.
+	Steps    []AgentStep   // The trace of every iteration.
+}
+
+// RunAgent sends a chat request and executes the tools requested by the model, sending their results back,
+// until the model replies without tool calls. The tool calls and their results are added to the chat.
+//
+// The run stops with ErrMaxIterations when the model still calls tools after the maximum number of iterations,
+// or with a context error when the time budget is exceeded. The result holds the trace of the run even on failure.
+// Token streaming can be enabled with WithStreamHandler, which is called for every iteration.
+//
+// Parameters:
+//   - chatId: The ID of the chat, or nil to not keep the conversation.
+//   - opts: The options of the run.
+//   - builder: The options of the requests and the initial messages, as passed to Chat.
+func (o *Ollama) RunAgent(chatId *string, opts AgentOptions, builder ...func(reqBuilder *ChatRequestBuilder)) (*AgentResult, error) {
+	base := ChatRequestBuilder{}
+	o.defaults.applyChat(&base)
+	for _, f := range builder {
+		f(&base)
+	}
+
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = 10
+	}
+
+	if opts.Tools != nil {
+		base.Tools = append(append([]Tool(nil), base.Tools...), opts.Tools.Tools()...)
+	}
+
+	ctx := o.ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	llm := o.WithContext(ctx)
+
+	result := &AgentResult{}
+	turn := base.Messages
+	var history []Message // The conversation, when no chat is kept
+
+	for i := 0; i < opts.MaxIterations; i++ {
+		start := time.Now()
+
+		req := base
+		req.Messages = append(append([]Message(nil), history...), turn...)
+
+		res, err := llm.Chat(chatId, func(r *ChatRequestBuilder) {
+			*r = req
+		})
+		if err != nil {
+			return result, err
+		}
+
+		step := AgentStep{Iteration: i, Messages: turn, Response: res}
+		result.Response = res
+
+		if len(res.Message.ToolCalls) == 0 {
+			step.Duration = time.Since(start)
+			result.Steps = append(result.Steps, step)
+			if opts.OnStep != nil {
+				opts.OnStep(step)
+			}
+			return result, nil
+		}
+
+		step.ToolCalls = callAgentTools(ctx, opts, res.Message.ToolCalls)
+
+		results := make([]Message, 0, len(step.ToolCalls))
+		for _, c := range step.ToolCalls {
+			results = append(results, c.Result)
+		}
+
+		// The results are added to the chat right away, so it stays consistent if the run stops here
+		if chatId != nil {
+			if err := llm.appendChat(*chatId, results...); err != nil {
+				return result, err
+			}
+			turn = nil
+		} else {
+			history = append(req.Messages, res.Message)
+			turn = results
+		}
+
+		step.Duration = time.Since(start)
+		result.Steps = append(result.Steps, step)
+		if opts.OnStep != nil {
+			opts.OnStep(step)
+		}
+
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+	}
+
+	return result, fmt.Errorf("%w (%d)", ErrMaxIterations, opts.MaxIterations)
+}
+
+// callAgentTools executes the tool calls of a reply and returns their results in order.
+func callAgentTools(ctx context.Context, opts AgentOptions, calls []ToolCall) []ToolCallResult {
+	results := make([]ToolCallResult, len(calls))
+
+	call := func(i int) {
+		start := time.Now()
+		c := calls[i]
+		r := ToolCallResult{Call: c, Approved: opts.Approve == nil || opts.Approve(ctx, c)}
+
+		switch {
+		case !r.Approved:
+			r.Err = fmt.Errorf("%w: %s", ErrToolCallDenied, c.Function.Name)
+			r.Result = NewToolMessage(c.Function.Name, toolErrorContent(r.Err))
+		case opts.Tools == nil:
+			r.Err = fmt.Errorf("%w: %s", ErrUnknownTool, c.Function.Name)
+			r.Result = NewToolMessage(c.Function.Name, toolErrorContent(r.Err))
+		default:
+			r.Result, r.Err = opts.Tools.Call(ctx, c)
+		}
+
+		r.Duration = time.Since(start)
+		results[i] = r
+	}
+
+	if !opts.Parallel {
+		for i := range calls {
+			call(i)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i := range calls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			call(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// appendChat adds the messages to the end of the chat, waiting for the current turn of the chat to end.
+func (o *Ollama) appendChat(chatId string, messages ...Message) error {
+	unlock := o.turns.Lock(chatId)
+	defer unlock()
+
+	return o.chats.Append(chatId, messages...)
+}
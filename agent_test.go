@@ -0,0 +1,135 @@
+package ollama
+
+import (
+	"context"
+	json2 "encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// agentServer is a fake chat endpoint that calls the get_weather and delete_city tools until it receives
+// their results, or forever if loop is set.
+func agentServer(t *testing.T, loop bool) *Ollama {
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequestBuilder
+		if err := json2.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Tools) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if n := len(req.Messages); !loop && *req.Messages[n-1].Role == "tool" {
+			json2.NewEncoder(w).Encode(ChatResponse{
+				Message: Message{Role: pointer("assistant"), Content: pointer(*req.Messages[n-2].Content + " " + *req.Messages[n-1].Content)},
+				Done:    true,
+			})
+			return
+		}
+
+		json2.NewEncoder(w).Encode(ChatResponse{
+			Message: Message{
+				Role:    pointer("assistant"),
+				Content: pointer(""),
+				ToolCalls: []ToolCall{
+					{Function: ToolCallFunction{Name: "get_weather", Arguments: map[string]interface{}{"city": "Paris"}}},
+					{Function: ToolCallFunction{Name: "delete_city", Arguments: map[string]interface{}{"city": "Paris"}}},
+				},
+			},
+			Done: true,
+		})
+	})
+}
+
+func agentTools(t *testing.T, calls *int32) *ToolRegistry {
+	tools := newWeatherRegistry(t)
+	err := RegisterTool(tools, "delete_city", "Delete a city", func(ctx context.Context, args weatherArgs) (string, error) {
+		atomic.AddInt32(calls, 1)
+		return "deleted", nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterTool returned an error: %s", err)
+	}
+	return tools
+}
+
+func TestRunAgent(t *testing.T) {
+	llm := agentServer(t, false)
+
+	var deleted int32
+	steps := 0
+	chatId := "agent"
+	res, err := llm.RunAgent(&chatId, AgentOptions{
+		Tools:    agentTools(t, &deleted),
+		Parallel: true,
+		Approve: func(ctx context.Context, call ToolCall) bool {
+			return call.Function.Name != "delete_city"
+		},
+		OnStep: func(step AgentStep) {
+			steps++
+		},
+	}, llm.Chat.WithModel("llama3.1"), llm.Chat.WithMessage(Message{Content: pointer("What is the weather in Paris?")}))
+	if err != nil {
+		t.Fatalf("RunAgent returned an error: %s", err)
+	}
+
+	if deleted != 0 {
+		t.Errorf("Expected the denied tool to not be called")
+	}
+
+	if len(res.Steps) != 2 || steps != 2 {
+		t.Fatalf("Expected 2 steps, got %d and %d", len(res.Steps), steps)
+	}
+
+	calls := res.Steps[0].ToolCalls
+	if len(calls) != 2 || !calls[0].Approved || calls[0].Err != nil || calls[1].Approved || !errors.Is(calls[1].Err, ErrToolCallDenied) {
+		t.Errorf("Unexpected tool calls: %+v", calls)
+	}
+
+	expected := `{"city":"Paris","temperature":21.5} {"error":"tool call denied: delete_city"}`
+	if *res.Response.Message.Content != expected {
+		t.Errorf("Expected the final answer to include the tool results, got %s", *res.Response.Message.Content)
+	}
+
+	history := mustGetChat(t, llm, chatId).Messages
+	if len(history) != 5 || len(history[1].ToolCalls) != 2 || *history[2].Role != "tool" || *history[3].Role != "tool" {
+		t.Errorf("Expected the tool calls and results to be stored, got %+v", history)
+	}
+
+	// Without a chat, the conversation is kept by the run
+	res, err = llm.RunAgent(nil, AgentOptions{Tools: agentTools(t, &deleted)}, llm.Chat.WithMessage(Message{Content: pointer("Delete Paris")}))
+	if err != nil {
+		t.Fatalf("RunAgent returned an error: %s", err)
+	}
+
+	if deleted != 1 || *res.Response.Message.Content != `{"city":"Paris","temperature":21.5} deleted` {
+		t.Errorf("Unexpected final answer: %s", *res.Response.Message.Content)
+	}
+}
+
+func TestRunAgentLimits(t *testing.T) {
+	llm := agentServer(t, true)
+
+	var deleted int32
+	res, err := llm.RunAgent(nil, AgentOptions{Tools: agentTools(t, &deleted), MaxIterations: 3}, llm.Chat.WithMessage(Message{Content: pointer("Loop")}))
+	if !errors.Is(err, ErrMaxIterations) {
+		t.Fatalf("Expected ErrMaxIterations, got %v", err)
+	}
+
+	if len(res.Steps) != 3 || deleted != 3 {
+		t.Errorf("Expected 3 iterations, got %d", len(res.Steps))
+	}
+
+	_, err = llm.RunAgent(nil, AgentOptions{
+		Tools:   agentTools(t, &deleted),
+		Timeout: 50 * time.Millisecond,
+		Approve: func(ctx context.Context, call ToolCall) bool {
+			<-ctx.Done()
+			return false
+		},
+	}, llm.Chat.WithMessage(Message{Content: pointer("Loop")}))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the time budget to stop the run, got %v", err)
+	}
+}
@@ -1,9 +1,80 @@
 package ollama
 
+import (
+	"fmt"
+	"sync"
+)
+
 // Chat stores the messages sent from the user and received from the assistant.
+// The methods of Chat are safe for concurrent use, except the first call on a Chat literal,
+// which creates its mutex. Chats created with NewChat or returned by the stores of this package can be shared right away.
 type Chat struct {
-	ID       string
-	Messages []Message
+	ID       string    `json:"id"`
+	Messages []Message `json:"messages"`
+
+	// HistoryPolicy controls which messages of the history are sent along with a request. If nil, all messages are sent.
+	HistoryPolicy *HistoryPolicy `json:"history_policy,omitempty"`
+
+	// SummaryPolicy condenses the older messages into Summary once the history grows too long. If nil, messages are never summarized.
+	SummaryPolicy *SummaryPolicy `json:"summary_policy,omitempty"`
+
+	Summary  string    `json:"summary,omitempty"`  // The summary of the archived messages.
+	Archived []Message `json:"archived,omitempty"` // The messages that were condensed into the summary.
+
+	// Alternatives stores, by message index, the candidate messages that were replaced, such as regenerated replies.
+	Alternatives map[int][]Message `json:"alternatives,omitempty"`
+
+	// KeepThinking stores the thinking of the replies in the chat, which is then sent back with the history.
+	KeepThinking bool `json:"keep_thinking,omitempty"`
+
+	mu *sync.RWMutex
+}
+
+// NewChat creates a new chat with the specified ID and messages.
+//
+// Parameters:
+//   - id: The ID of the chat.
+//   - messages: The initial messages of the chat.
+func NewChat(id string, messages ...Message) *Chat {
+	return &Chat{
+		ID:       id,
+		Messages: append(make([]Message, 0, len(messages)), messages...),
+		mu:       &sync.RWMutex{},
+	}
+}
+
+// locker returns the mutex of the chat. The chats created with NewChat or returned by the stores of this package
+// have one, while a Chat literal gets it on its first use, so that use must not be concurrent.
+func (c *Chat) locker() *sync.RWMutex {
+	if c.mu == nil {
+		c.mu = &sync.RWMutex{}
+	}
+	return c.mu
+}
+
+// clone returns a copy of the chat that does not share its messages.
+func (c *Chat) clone() *Chat {
+	mu := c.locker()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	clone := NewChat(c.ID, c.Messages...)
+	clone.HistoryPolicy = c.HistoryPolicy
+	clone.SummaryPolicy = c.SummaryPolicy
+	clone.Summary = c.Summary
+	clone.KeepThinking = c.KeepThinking
+	clone.Archived = append([]Message(nil), c.Archived...)
+	clone.Alternatives = cloneAlternatives(c.Alternatives, len(c.Messages))
+	return clone
+}
+
+// GetMessages returns a copy of the messages of the chat.
+func (c *Chat) GetMessages() []Message {
+	mu := c.locker()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append(make([]Message, 0, len(c.Messages)), c.Messages...)
 }
 
 // AddMessage adds a new message to the end of the chat.
@@ -11,27 +82,201 @@ type Chat struct {
 // Parameters:
 //   - m: The message to add.
 func (c *Chat) AddMessage(m Message) {
+	mu := c.locker()
+	mu.Lock()
+	defer mu.Unlock()
+
 	c.Messages = append(c.Messages, m)
 }
 
-// AddMessageTo adds a new message at the specified index.
+// AddMessageTo adds a new message at the specified index. The alternatives of the following messages move along with them.
 //
 // Parameters:
 //   - index: The index at which to add the new message.
 //   - m: The message to add.
 func (c *Chat) AddMessageTo(index int, m Message) {
+	mu := c.locker()
+	mu.Lock()
+	defer mu.Unlock()
+
 	c.Messages = append(c.Messages[:index], append([]Message{m}, c.Messages[index:]...)...)
+	c.Alternatives = shiftAlternatives(c.Alternatives, index, 1)
 }
 
-// DeleteMessage deletes a message at the specified index.
+// DeleteMessage deletes a message at the specified index, along with its alternatives.
+// The alternatives of the following messages move along with them.
 //
 // Parameters:
 //   - index: The index of the message to delete.
 func (c *Chat) DeleteMessage(index int) {
+	mu := c.locker()
+	mu.Lock()
+	defer mu.Unlock()
+
 	c.Messages = append(c.Messages[:index], c.Messages[index+1:]...)
+	delete(c.Alternatives, index)
+	c.Alternatives = shiftAlternatives(c.Alternatives, index+1, -1)
 }
 
 // DeleteAllMessages deletes all messages in the chat.
 func (c *Chat) DeleteAllMessages() {
+	mu := c.locker()
+	mu.Lock()
+	defer mu.Unlock()
+
 	c.Messages = make([]Message, 0)
 }
+
+// Fork creates a new chat that contains the messages before the specified index,
+// to branch the conversation from an earlier point, for example to edit a message.
+// The policies, the summary and the alternatives of the kept messages are copied to the new chat.
+//
+// Parameters:
+//   - atIndex: The index of the first message that is not copied, from 0 to the number of messages.
+//   - newID: The ID of the new chat.
+func (c *Chat) Fork(atIndex int, newID string) (*Chat, error) {
+	mu := c.locker()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if atIndex < 0 || atIndex > len(c.Messages) {
+		return nil, fmt.Errorf("fork: index %d out of range for a chat of %d messages", atIndex, len(c.Messages))
+	}
+
+	fork := NewChat(newID, c.Messages[:atIndex]...)
+	fork.HistoryPolicy = c.HistoryPolicy
+	fork.SummaryPolicy = c.SummaryPolicy
+	fork.Summary = c.Summary
+	fork.KeepThinking = c.KeepThinking
+	fork.Archived = append([]Message(nil), c.Archived...)
+	fork.Alternatives = cloneAlternatives(c.Alternatives, atIndex)
+	return fork, nil
+}
+
+// RewindTo deletes the messages from the specified index onwards, along with their alternatives.
+//
+// Parameters:
+//   - index: The index of the first message to delete, from 0 to the number of messages.
+func (c *Chat) RewindTo(index int) error {
+	mu := c.locker()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if index < 0 || index > len(c.Messages) {
+		return fmt.Errorf("rewind: index %d out of range for a chat of %d messages", index, len(c.Messages))
+	}
+
+	c.Messages = c.Messages[:index]
+	c.Alternatives = cloneAlternatives(c.Alternatives, index)
+	return nil
+}
+
+// AddAlternative stores a candidate message for the message at the specified index.
+//
+// Parameters:
+//   - index: The index of the message.
+//   - m: The alternative message.
+func (c *Chat) AddAlternative(index int, m Message) {
+	mu := c.locker()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c.Alternatives == nil {
+		c.Alternatives = make(map[int][]Message)
+	}
+	c.Alternatives[index] = append(c.Alternatives[index], m)
+}
+
+// SelectAlternative replaces the message at the specified index with one of its alternatives.
+// The replaced message becomes an alternative itself, so the selection can be switched back.
+//
+// Parameters:
+//   - index: The index of the message.
+//   - alternative: The index of the alternative in Alternatives[index].
+func (c *Chat) SelectAlternative(index, alternative int) error {
+	mu := c.locker()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if index < 0 || index >= len(c.Messages) {
+		return fmt.Errorf("select alternative: index %d out of range for a chat of %d messages", index, len(c.Messages))
+	}
+
+	if alternative < 0 || alternative >= len(c.Alternatives[index]) {
+		return fmt.Errorf("select alternative: message %d has no alternative %d", index, alternative)
+	}
+
+	c.Messages[index], c.Alternatives[index][alternative] = c.Alternatives[index][alternative], c.Messages[index]
+	return nil
+}
+
+// cloneAlternatives copies the alternatives of the messages before the specified index.
+func cloneAlternatives(alternatives map[int][]Message, before int) map[int][]Message {
+	if len(alternatives) == 0 {
+		return nil
+	}
+
+	res := make(map[int][]Message, len(alternatives))
+	for i, m := range alternatives {
+		if i < before {
+			res[i] = append([]Message(nil), m...)
+		}
+	}
+	return res
+}
+
+// shiftAlternatives moves the alternatives of the messages from the specified index onwards by delta positions.
+func shiftAlternatives(alternatives map[int][]Message, from, delta int) map[int][]Message {
+	if len(alternatives) == 0 {
+		return alternatives
+	}
+
+	res := make(map[int][]Message, len(alternatives))
+	for i, m := range alternatives {
+		if i >= from {
+			i += delta
+		}
+		res[i] = m
+	}
+	return res
+}
+
+// keyedMutex provides a mutex for each key. The mutex of a key is removed once it is no longer used.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedMutexEntry
+}
+
+type keyedMutexEntry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Lock locks the mutex of the key and returns the function that unlocks it.
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedMutexEntry)
+	}
+
+	entry := k.locks[key]
+	if entry == nil {
+		entry = &keyedMutexEntry{}
+		k.locks[key] = entry
+	}
+	entry.refs++
+	k.mu.Unlock()
+
+	entry.mu.Lock()
+
+	return func() {
+		entry.mu.Unlock()
+
+		k.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,368 @@
+package ollama
+
+import (
+	"bufio"
+	"encoding/base64"
+	json2 "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The transcript formats only contain the messages of a chat;
+// the policies, the summary, the archived messages and the alternatives are not exported.
+// Tool calls and tool names are only kept by the JSONL format.
+
+// ExportMarkdown writes the messages of the chat as a Markdown transcript.
+// Each message starts with a "## role" heading, followed by its content and its images as data URLs.
+// Content lines that would be read as a heading or an image are escaped with a backslash,
+// so the transcript can be read back with ImportMarkdown.
+//
+// Parameters:
+//   - w: The writer of the transcript.
+func (c *Chat) ExportMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	if len(c.ID) != 0 {
+		fmt.Fprintf(bw, "# %s\n\n", c.ID)
+	}
+
+	for _, m := range c.GetMessages() {
+		role, content := "user", ""
+		if m.Role != nil {
+			role = *m.Role
+		}
+		if m.Content != nil {
+			content = *m.Content
+		}
+
+		fmt.Fprintf(bw, "## %s\n\n", role)
+		for _, line := range strings.Split(content, "\n") {
+			bw.WriteString(escapeMarkdownLine(line) + "\n")
+		}
+
+		if len(m.Images) != 0 {
+			bw.WriteString("\n")
+			for _, image := range m.Images {
+				fmt.Fprintf(bw, "![image](%s)\n", imageDataURL(image))
+			}
+		}
+
+		bw.WriteString("\n")
+	}
+
+	return bw.Flush()
+}
+
+// ImportMarkdown reads a chat from a Markdown transcript written by ExportMarkdown.
+// Anything before the first "## role" heading, such as the title, is ignored.
+//
+// Parameters:
+//   - id: The ID of the new chat.
+//   - r: The reader of the transcript.
+func ImportMarkdown(id string, r io.Reader) (*Chat, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	chat := NewChat(id)
+
+	var role string
+	var section []string
+	flush := func() error {
+		if len(role) == 0 {
+			return nil
+		}
+
+		m, err := parseMarkdownMessage(role, section)
+		if err != nil {
+			return err
+		}
+
+		chat.Messages = append(chat.Messages, m)
+		return nil
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if strings.HasPrefix(line, "## ") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+
+			role = strings.TrimSpace(strings.TrimPrefix(line, "## "))
+			section = section[:0]
+			continue
+		}
+
+		section = append(section, line)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// parseMarkdownMessage parses the lines that follow a "## role" heading.
+func parseMarkdownMessage(role string, lines []string) (Message, error) {
+	// Remove the blank lines that separate the content from the headings.
+	if len(lines) != 0 && len(lines[0]) == 0 {
+		lines = lines[1:]
+	}
+	if len(lines) != 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	var images []string
+	end := len(lines)
+	for end > 0 && strings.HasPrefix(lines[end-1], "![") {
+		end--
+	}
+
+	for _, line := range lines[end:] {
+		start, stop := strings.Index(line, "]("), strings.LastIndex(line, ")")
+		if start < 0 || stop < start {
+			return Message{}, fmt.Errorf("markdown transcript: malformed image %q", line)
+		}
+
+		image, err := imageFromDataURL(line[start+2 : stop])
+		if err != nil {
+			return Message{}, fmt.Errorf("markdown transcript: %w", err)
+		}
+		images = append(images, image)
+	}
+
+	if len(images) != 0 && end > 0 && len(lines[end-1]) == 0 {
+		end--
+	}
+
+	content := make([]string, 0, end)
+	for _, line := range lines[:end] {
+		content = append(content, unescapeMarkdownLine(line))
+	}
+
+	return Message{
+		Role:    pointer(role),
+		Content: pointer(strings.Join(content, "\n")),
+		Images:  images,
+	}, nil
+}
+
+// isMarkdownMarkup reports whether the line, without its leading backslashes, would be read as a heading or an image.
+func isMarkdownMarkup(line string) bool {
+	line = strings.TrimLeft(line, "\\")
+	return strings.HasPrefix(line, "## ") || strings.HasPrefix(line, "![")
+}
+
+func escapeMarkdownLine(line string) string {
+	if isMarkdownMarkup(line) {
+		return "\\" + line
+	}
+	return line
+}
+
+func unescapeMarkdownLine(line string) string {
+	if strings.HasPrefix(line, "\\") && isMarkdownMarkup(line) {
+		return line[1:]
+	}
+	return line
+}
+
+// ExportJSONL writes the messages of the chat as JSON lines, one Message per line.
+//
+// Parameters:
+//   - w: The writer of the messages.
+func (c *Chat) ExportJSONL(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	encoder := json2.NewEncoder(bw)
+
+	for _, m := range c.GetMessages() {
+		if err := encoder.Encode(m); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ImportJSONL reads a chat from JSON lines, one Message per line. Blank lines are ignored.
+// A malformed line is reported as a *StreamDecodeError.
+//
+// Parameters:
+//   - id: The ID of the new chat.
+//   - r: The reader of the messages.
+func ImportJSONL(id string, r io.Reader) (*Chat, error) {
+	chat := NewChat(id)
+
+	decoder := newStreamDecoder(r, 4096)
+	for {
+		line, err := decoder.Next()
+		if err == io.EOF {
+			return chat, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := bodyTo[Message](line)
+		if err != nil {
+			return nil, &StreamDecodeError{Line: line, Err: err}
+		}
+		chat.Messages = append(chat.Messages, *m)
+	}
+}
+
+// openAIMessage is a message of the OpenAI chat completions API.
+// Content is either a string or an array of openAIContentPart.
+type openAIMessage struct {
+	Role    string           `json:"role"`
+	Content json2.RawMessage `json:"content"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"` // Either "text" or "image_url".
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// ExportOpenAI writes the messages of the chat as an OpenAI "messages" array.
+// Messages with images are written as content parts, with the images as base64 data URLs.
+//
+// Parameters:
+//   - w: The writer of the JSON array.
+func (c *Chat) ExportOpenAI(w io.Writer) error {
+	messages := c.GetMessages()
+	res := make([]openAIMessage, 0, len(messages))
+
+	for _, m := range messages {
+		role, content := "user", ""
+		if m.Role != nil {
+			role = *m.Role
+		}
+		if m.Content != nil {
+			content = *m.Content
+		}
+
+		var v interface{} = content
+		if len(m.Images) != 0 {
+			parts := make([]openAIContentPart, 0, len(m.Images)+1)
+			if len(content) != 0 {
+				parts = append(parts, openAIContentPart{Type: "text", Text: content})
+			}
+			for _, image := range m.Images {
+				parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: imageDataURL(image)}})
+			}
+			v = parts
+		}
+
+		data, err := json2.Marshal(v)
+		if err != nil {
+			return err
+		}
+		res = append(res, openAIMessage{Role: role, Content: data})
+	}
+
+	return json2.NewEncoder(w).Encode(res)
+}
+
+// ImportOpenAI reads a chat from an OpenAI "messages" array.
+// The text parts of a message are joined with newlines and its images must be base64 data URLs.
+// The "developer" role is mapped to "system".
+//
+// Parameters:
+//   - id: The ID of the new chat.
+//   - r: The reader of the JSON array.
+func ImportOpenAI(id string, r io.Reader) (*Chat, error) {
+	var messages []openAIMessage
+	if err := json2.NewDecoder(r).Decode(&messages); err != nil {
+		return nil, err
+	}
+
+	chat := NewChat(id)
+	for i, om := range messages {
+		role := om.Role
+		if role == "developer" {
+			role = "system"
+		}
+
+		m := Message{Role: pointer(role), Content: pointer("")}
+
+		content := strings.TrimSpace(string(om.Content))
+		switch {
+		case len(content) == 0 || content == "null":
+		case strings.HasPrefix(content, "\""):
+			if err := json2.Unmarshal(om.Content, m.Content); err != nil {
+				return nil, fmt.Errorf("openai message %d: %w", i, err)
+			}
+		default:
+			var parts []openAIContentPart
+			if err := json2.Unmarshal(om.Content, &parts); err != nil {
+				return nil, fmt.Errorf("openai message %d: %w", i, err)
+			}
+
+			texts := make([]string, 0, len(parts))
+			for _, part := range parts {
+				switch part.Type {
+				case "text":
+					texts = append(texts, part.Text)
+				case "image_url":
+					if part.ImageURL == nil {
+						return nil, fmt.Errorf("openai message %d: image part without url", i)
+					}
+
+					image, err := imageFromDataURL(part.ImageURL.URL)
+					if err != nil {
+						return nil, fmt.Errorf("openai message %d: %w", i, err)
+					}
+					m.Images = append(m.Images, image)
+				default:
+					return nil, fmt.Errorf("openai message %d: unsupported content part %q", i, part.Type)
+				}
+			}
+			m.Content = pointer(strings.Join(texts, "\n"))
+		}
+
+		chat.Messages = append(chat.Messages, m)
+	}
+
+	return chat, nil
+}
+
+// imageDataURL returns the data URL of a base64 encoded image, detecting its media type from its first bytes.
+func imageDataURL(image string) string {
+	prefix := image
+	if len(prefix) > 64 {
+		prefix = prefix[:64]
+	}
+
+	mediaType := "image/png"
+	if data, err := base64.StdEncoding.DecodeString(prefix[:len(prefix)/4*4]); err == nil {
+		if t := http.DetectContentType(data); strings.HasPrefix(t, "image/") {
+			mediaType = t
+		}
+	}
+
+	return "data:" + mediaType + ";base64," + image
+}
+
+// imageFromDataURL returns the base64 encoded image of a data URL.
+func imageFromDataURL(v string) (string, error) {
+	if !strings.HasPrefix(v, "data:") {
+		return "", errors.New("only data URLs are supported for images")
+	}
+
+	header, data, ok := strings.Cut(strings.TrimPrefix(v, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return "", errors.New("only base64 data URLs are supported for images")
+	}
+
+	return data, nil
+}
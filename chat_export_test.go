@@ -0,0 +1,80 @@
+package ollama
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func exportTestChat() *Chat {
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+
+	return NewChat("export",
+		Message{Role: pointer("system"), Content: pointer("Be brief.")},
+		Message{Role: pointer("user"), Content: pointer("What is in this picture?\n\n## Not a heading\n\\## Escaped\n![not an image](x)\n"), Images: []string{png, png}},
+		Message{Role: pointer("assistant"), Content: pointer("")},
+		Message{Role: pointer("user"), Content: pointer("\n  indented\n\n"), Images: []string{png}},
+		Message{Role: pointer("assistant"), Content: pointer("A cat.")},
+	)
+}
+
+func TestChatExportRoundTrip(t *testing.T) {
+	formats := []struct {
+		name   string
+		export func(c *Chat, b *bytes.Buffer) error
+		parse  func(id string, b *bytes.Buffer) (*Chat, error)
+	}{
+		{"markdown", func(c *Chat, b *bytes.Buffer) error { return c.ExportMarkdown(b) }, func(id string, b *bytes.Buffer) (*Chat, error) { return ImportMarkdown(id, b) }},
+		{"jsonl", func(c *Chat, b *bytes.Buffer) error { return c.ExportJSONL(b) }, func(id string, b *bytes.Buffer) (*Chat, error) { return ImportJSONL(id, b) }},
+		{"openai", func(c *Chat, b *bytes.Buffer) error { return c.ExportOpenAI(b) }, func(id string, b *bytes.Buffer) (*Chat, error) { return ImportOpenAI(id, b) }},
+	}
+
+	chat := exportTestChat()
+	for _, f := range formats {
+		var b bytes.Buffer
+		if err := f.export(chat, &b); err != nil {
+			t.Fatalf("%s: export returned an error: %s", f.name, err)
+		}
+		exported := b.String()
+
+		imported, err := f.parse("imported", &b)
+		if err != nil {
+			t.Fatalf("%s: import returned an error: %s", f.name, err)
+		}
+
+		if imported.ID != "imported" || !reflect.DeepEqual(imported.Messages, chat.Messages) {
+			t.Errorf("%s: round trip changed the messages:\n%s", f.name, exported)
+		}
+	}
+}
+
+func TestChatExportOpenAIImages(t *testing.T) {
+	var b bytes.Buffer
+	if err := exportTestChat().ExportOpenAI(&b); err != nil {
+		t.Fatalf("ExportOpenAI returned an error: %s", err)
+	}
+
+	if !strings.Contains(b.String(), `"url":"data:image/png;base64,`) {
+		t.Errorf("Expected the images as PNG data URLs, got %s", b.String())
+	}
+
+	chat, err := ImportOpenAI("openai", strings.NewReader(`[
+		{"role":"developer","content":"Be brief."},
+		{"role":"user","content":[{"type":"text","text":"Look"},{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,/9j/"}}]},
+		{"role":"assistant","content":null}
+	]`))
+	if err != nil {
+		t.Fatalf("ImportOpenAI returned an error: %s", err)
+	}
+
+	if *chat.Messages[0].Role != "system" || *chat.Messages[1].Content != "Look" || chat.Messages[1].Images[0] != "/9j/" || *chat.Messages[2].Content != "" {
+		t.Errorf("Unexpected imported messages: %+v", chat.Messages)
+	}
+
+	_, err = ImportOpenAI("openai", strings.NewReader(`[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}]`))
+	if err == nil {
+		t.Errorf("Expected an error for an image that is not a data URL")
+	}
+}
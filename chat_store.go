@@ -0,0 +1,430 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	json2 "encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChatStore stores the chats used by the client.
+// The chats returned by a store are copies; changes to them must be saved with Put.
+// Implementations must be safe for concurrent use.
+type ChatStore interface {
+	// Get returns the chat with the specified ID, or nil if it does not exist.
+	Get(id string) (*Chat, error)
+
+	// Put stores the chat, replacing any chat with the same ID.
+	Put(chat *Chat) error
+
+	// Delete removes the chat with the specified ID. Deleting a chat that does not exist is not an error.
+	Delete(id string) error
+
+	// List returns the IDs of all the stored chats.
+	List() ([]string, error)
+
+	// Append adds the messages to the end of the chat with the specified ID, creating the chat if it does not exist.
+	Append(id string, messages ...Message) error
+}
+
+// MemoryChatStore is a ChatStore that keeps the chats in memory.
+// A restart of the application will delete all chats.
+type MemoryChatStore struct {
+	mu    sync.RWMutex
+	chats map[string]*Chat
+}
+
+// NewMemoryChatStore creates a new empty MemoryChatStore.
+func NewMemoryChatStore() *MemoryChatStore {
+	return &MemoryChatStore{
+		chats: make(map[string]*Chat),
+	}
+}
+
+// Get returns a copy of the chat with the specified ID, or nil if it does not exist.
+func (s *MemoryChatStore) Get(id string) (*Chat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	chat := s.chats[id]
+	if chat == nil {
+		return nil, nil
+	}
+	return chat.clone(), nil
+}
+
+// Put stores a copy of the chat.
+func (s *MemoryChatStore) Put(chat *Chat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chats[chat.ID] = chat.clone()
+	return nil
+}
+
+// Delete removes the chat with the specified ID.
+func (s *MemoryChatStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.chats, id)
+	return nil
+}
+
+// List returns the sorted IDs of all the stored chats.
+func (s *MemoryChatStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.chats))
+	for id := range s.chats {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Append adds the messages to the end of the chat, creating the chat if it does not exist.
+func (s *MemoryChatStore) Append(id string, messages ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat := s.chats[id]
+	if chat == nil {
+		chat = NewChat(id)
+		s.chats[id] = chat
+	}
+
+	chat.Messages = append(chat.Messages, messages...)
+	return nil
+}
+
+// FileChatStore is a ChatStore that keeps each chat in a JSON file in a directory.
+// Files are replaced atomically, so the directory can be read by other processes on the same host.
+// Writes to the same chat from different processes are not coordinated.
+type FileChatStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileChatStore creates a FileChatStore that stores the chats in dir, creating the directory if needed.
+//
+// Parameters:
+//   - dir: The directory of the chat files.
+func NewFileChatStore(dir string) (*FileChatStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileChatStore{dir: dir}, nil
+}
+
+func (s *FileChatStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".json")
+}
+
+// Get reads the chat with the specified ID, or returns nil if it does not exist.
+func (s *FileChatStore) Get(id string) (*Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(id)
+}
+
+func (s *FileChatStore) read(id string) (*Chat, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := bodyTo[Chat](data)
+	if err != nil {
+		return nil, err
+	}
+	chat.mu = &sync.RWMutex{}
+	return chat, nil
+}
+
+// Put writes the chat to its file.
+func (s *FileChatStore) Put(chat *Chat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(chat)
+}
+
+func (s *FileChatStore) write(chat *Chat) error {
+	data, err := json2.Marshal(chat.clone())
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, ".chat-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), s.path(chat.ID))
+}
+
+// Delete removes the file of the chat with the specified ID.
+func (s *FileChatStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// List returns the sorted IDs of all the chats in the directory.
+func (s *FileChatStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		id, err := url.PathUnescape(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Append adds the messages to the end of the chat, creating the chat if it does not exist.
+func (s *FileChatStore) Append(id string, messages ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat, err := s.read(id)
+	if err != nil {
+		return err
+	}
+
+	if chat == nil {
+		chat = NewChat(id)
+	}
+
+	chat.Messages = append(chat.Messages, messages...)
+	return s.write(chat)
+}
+
+// JSONLChatStore is a ChatStore that records every change as a line in an append-only JSONL file.
+// Every line is written with a single append, so the file can be shared by processes on the same host;
+// each store picks up the changes made by others before every operation.
+type JSONLChatStore struct {
+	path string
+
+	mu     sync.Mutex
+	offset int64
+	chats  map[string]*Chat
+}
+
+// jsonlChatRecord is a line of the JSONL chat file.
+type jsonlChatRecord struct {
+	Op       string    `json:"op"` // Either "put", "append" or "delete".
+	ID       string    `json:"id"`
+	Chat     *Chat     `json:"chat,omitempty"`
+	Messages []Message `json:"messages,omitempty"`
+}
+
+// NewJSONLChatStore creates a JSONLChatStore that records the chats in the file at path, creating it if needed.
+//
+// Parameters:
+//   - path: The path of the JSONL file.
+func NewJSONLChatStore(path string) (*JSONLChatStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	s := &JSONLChatStore{
+		path:  path,
+		chats: make(map[string]*Chat),
+	}
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// refresh applies the lines written to the file since the last refresh.
+func (s *JSONLChatStore) refresh() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// An incomplete line is still being written; it will be read on the next refresh.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		s.offset += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		record, err := bodyTo[jsonlChatRecord](line)
+		if err != nil {
+			return &StreamDecodeError{Line: line, Err: err}
+		}
+		s.apply(record)
+	}
+}
+
+func (s *JSONLChatStore) apply(record *jsonlChatRecord) {
+	switch record.Op {
+	case "put":
+		if record.Chat != nil {
+			chat := record.Chat.clone()
+			chat.ID = record.ID
+			s.chats[record.ID] = chat
+		}
+	case "append":
+		chat := s.chats[record.ID]
+		if chat == nil {
+			chat = NewChat(record.ID)
+			s.chats[record.ID] = chat
+		}
+		chat.Messages = append(chat.Messages, record.Messages...)
+	case "delete":
+		delete(s.chats, record.ID)
+	}
+}
+
+// write appends the record to the file and applies it.
+func (s *JSONLChatStore) write(record *jsonlChatRecord) error {
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	data, err := json2.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return s.refresh()
+}
+
+// Get returns a copy of the chat with the specified ID, or nil if it does not exist.
+func (s *JSONLChatStore) Get(id string) (*Chat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	chat := s.chats[id]
+	if chat == nil {
+		return nil, nil
+	}
+	return chat.clone(), nil
+}
+
+// Put records the chat, replacing any chat with the same ID.
+func (s *JSONLChatStore) Put(chat *Chat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(&jsonlChatRecord{Op: "put", ID: chat.ID, Chat: chat.clone()})
+}
+
+// Delete records the deletion of the chat with the specified ID.
+func (s *JSONLChatStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(&jsonlChatRecord{Op: "delete", ID: id})
+}
+
+// List returns the sorted IDs of all the chats.
+func (s *JSONLChatStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(s.chats))
+	for id := range s.chats {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Append records the messages added to the end of the chat.
+func (s *JSONLChatStore) Append(id string, messages ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(&jsonlChatRecord{Op: "append", ID: id, Messages: messages})
+}
@@ -0,0 +1,127 @@
+package ollama
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestChatStores(t *testing.T) {
+	dir := t.TempDir()
+
+	stores := map[string]func() (ChatStore, error){
+		"memory": func() (ChatStore, error) {
+			return NewMemoryChatStore(), nil
+		},
+		"file": func() (ChatStore, error) {
+			return NewFileChatStore(filepath.Join(dir, "chats"))
+		},
+		"jsonl": func() (ChatStore, error) {
+			return NewJSONLChatStore(filepath.Join(dir, "chats.jsonl"))
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, err := open()
+			if err != nil {
+				t.Fatalf("Failed to open store: %s", err)
+			}
+
+			user := Message{Role: pointer("user"), Content: pointer("Hello")}
+			assistant := Message{Role: pointer("assistant"), Content: pointer("Hi")}
+
+			if err := store.Put(NewChat("a/b", user)); err != nil {
+				t.Fatalf("Put returned an error: %s", err)
+			}
+			if err := store.Append("a/b", assistant); err != nil {
+				t.Fatalf("Append returned an error: %s", err)
+			}
+			if err := store.Append("c", user); err != nil {
+				t.Fatalf("Append returned an error: %s", err)
+			}
+			if err := store.Put(NewChat("d")); err != nil {
+				t.Fatalf("Put returned an error: %s", err)
+			}
+			if err := store.Delete("d"); err != nil {
+				t.Fatalf("Delete returned an error: %s", err)
+			}
+
+			// The persistent stores must keep the chats when they are opened again.
+			if name != "memory" {
+				if store, err = open(); err != nil {
+					t.Fatalf("Failed to reopen store: %s", err)
+				}
+			}
+
+			ids, err := store.List()
+			if err != nil {
+				t.Fatalf("List returned an error: %s", err)
+			}
+			if !reflect.DeepEqual(ids, []string{"a/b", "c"}) {
+				t.Errorf("Unexpected chat IDs: %v", ids)
+			}
+
+			chat, err := store.Get("a/b")
+			if err != nil {
+				t.Fatalf("Get returned an error: %s", err)
+			}
+			if chat == nil || !reflect.DeepEqual(chat.Messages, []Message{user, assistant}) {
+				t.Errorf("Unexpected chat: %+v", chat)
+			}
+
+			// A returned chat can be shared between goroutines right away.
+			shared, err := store.Get("c")
+			if err != nil {
+				t.Fatalf("Get returned an error: %s", err)
+			}
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					shared.AddMessage(assistant)
+				}()
+			}
+			wg.Wait()
+			if len(shared.GetMessages()) != 11 {
+				t.Errorf("Expected the concurrent messages to be added, got %d messages", len(shared.GetMessages()))
+			}
+
+			// Changes to a returned chat must not affect the store until it is put.
+			chat.AddMessage(user)
+			if stored, _ := store.Get("a/b"); len(stored.Messages) != 2 {
+				t.Errorf("Expected the stored chat to be unchanged, got %d messages", len(stored.Messages))
+			}
+
+			if chat, _ := store.Get("d"); chat != nil {
+				t.Errorf("Expected deleted chat to not exist, got %+v", chat)
+			}
+		})
+	}
+}
+
+func TestFileChatStoreOldFieldNames(t *testing.T) {
+	dir := t.TempDir()
+
+	// A chat serialized before the Chat fields had json tags
+	data := `{"ID":"old","Messages":[{"role":"user","content":"hi","images":null}]}`
+	if err := os.WriteFile(filepath.Join(dir, "old.json"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileChatStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chat, err := store.Get("old")
+	if err != nil {
+		t.Fatalf("Get returned an error: %s", err)
+	}
+	if chat == nil || chat.ID != "old" || len(chat.Messages) != 1 || *chat.Messages[0].Content != "hi" {
+		t.Errorf("Expected the chat to be loaded, got %+v", chat)
+	}
+}
@@ -0,0 +1,253 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// chatServer is a fake chat endpoint that replies with the number of messages it received,
+// or fails if the content of the last message is "fail".
+func chatServer(t *testing.T) (*Ollama, *[][]Message) {
+	var mu sync.Mutex
+	requests := make([][]Message, 0)
+
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequestBuilder
+		if err := json2.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		requests = append(requests, req.Messages)
+		mu.Unlock()
+
+		if n := len(req.Messages); n > 0 && *req.Messages[n-1].Content == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed"}`))
+			return
+		}
+
+		json2.NewEncoder(w).Encode(ChatResponse{
+			Model:   "llama3",
+			Message: Message{Role: pointer("assistant"), Content: pointer(fmt.Sprint(len(req.Messages)))},
+			Done:    true,
+		})
+	})
+
+	return llm, &requests
+}
+
+func mustGetChat(t *testing.T, llm *Ollama, id string) *Chat {
+	chat, err := llm.GetChat(id)
+	if err != nil {
+		t.Fatalf("GetChat returned an error: %s", err)
+	}
+	if chat == nil {
+		t.Fatalf("Chat %s does not exist", id)
+	}
+	return chat
+}
+
+func TestChatConcurrent(t *testing.T) {
+	llm, _ := chatServer(t)
+	chatId := "concurrent"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := llm.Chat(&chatId, llm.Chat.WithModel("llama3"))
+			if err != nil {
+				t.Errorf("Chat returned an error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	messages := mustGetChat(t, llm, chatId).GetMessages()
+	if len(messages) != 20 {
+		t.Fatalf("Expected 20 messages, got %d", len(messages))
+	}
+
+	// Each request must have seen all the replies of the previous ones.
+	for i, m := range messages {
+		if *m.Content != fmt.Sprint(i) {
+			t.Errorf("Expected message %d to be %d, got %s", i, i, *m.Content)
+		}
+	}
+}
+
+func TestChatHistoryOrder(t *testing.T) {
+	llm, requests := chatServer(t)
+	chatId := "order"
+
+	for _, content := range []string{"first", "second", "fail", "third"} {
+		_, err := llm.Chat(&chatId, llm.Chat.WithModel("llama3"), llm.Chat.WithMessage(Message{Content: pointer(content)}))
+		if (err != nil) != (content == "fail") {
+			t.Fatalf("Unexpected error for %s: %v", content, err)
+		}
+	}
+
+	contents := func(messages []Message) []string {
+		res := make([]string, 0, len(messages))
+		for _, m := range messages {
+			res = append(res, *m.Role+":"+*m.Content)
+		}
+		return res
+	}
+
+	expected := []string{"user:first", "assistant:1", "user:second", "assistant:3", "user:third", "assistant:5"}
+
+	last := (*requests)[len(*requests)-1]
+	if got := contents(last); fmt.Sprint(got) != fmt.Sprint(expected[:5]) {
+		t.Errorf("Expected the request to contain %v, got %v", expected[:5], got)
+	}
+
+	if got := contents(mustGetChat(t, llm, chatId).Messages); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected the chat to contain %v, got %v", expected, got)
+	}
+}
+
+func TestChatRegenerate(t *testing.T) {
+	llm, requests := chatServer(t)
+	chatId := "regenerate"
+
+	for _, content := range []string{"a", "b"} {
+		_, err := llm.Chat(&chatId, llm.Chat.WithModel("llama3"), llm.Chat.WithMessage(Message{Role: pointer("user"), Content: pointer(content)}))
+		if err != nil {
+			t.Fatalf("Chat returned an error: %s", err)
+		}
+	}
+
+	// The history is [a 1 b 3]; regenerating resends [a 1 b].
+	res, err := llm.Regenerate(chatId, true, llm.Chat.WithModel("llama3"))
+	if err != nil {
+		t.Fatalf("Regenerate returned an error: %s", err)
+	}
+
+	last := (*requests)[len(*requests)-1]
+	if len(last) != 3 || *last[2].Content != "b" {
+		t.Errorf("Expected the history up to the last user message to be sent, got %d messages", len(last))
+	}
+
+	chat := mustGetChat(t, llm, chatId)
+	if len(chat.Messages) != 4 || *chat.Messages[3].Content != *res.Message.Content {
+		t.Fatalf("Expected the reply to be replaced, got %+v", chat.Messages)
+	}
+
+	if len(chat.Alternatives[3]) != 1 || *chat.Alternatives[3][0].Content != "3" {
+		t.Fatalf("Expected the old reply to be kept as an alternative, got %+v", chat.Alternatives)
+	}
+
+	chat.Messages[3].Content = pointer("new")
+	if err := chat.SelectAlternative(3, 0); err != nil {
+		t.Fatalf("SelectAlternative returned an error: %s", err)
+	}
+	if *chat.Messages[3].Content != "3" || *chat.Alternatives[3][0].Content != "new" {
+		t.Errorf("Expected the alternative to be swapped with the reply")
+	}
+
+	for _, tt := range [][2]int{{-1, 0}, {4, 0}, {3, 1}, {3, -1}, {1, 0}} {
+		if err := chat.SelectAlternative(tt[0], tt[1]); err == nil {
+			t.Errorf("Expected an error when selecting the alternative %d of message %d", tt[1], tt[0])
+		}
+	}
+
+	fork, err := chat.Fork(2, "fork")
+	if err != nil {
+		t.Fatalf("Fork returned an error: %s", err)
+	}
+	if fork.ID != "fork" || len(fork.Messages) != 2 || len(fork.Alternatives) != 0 {
+		t.Errorf("Unexpected fork: %+v", fork)
+	}
+
+	for _, atIndex := range []int{-1, 5} {
+		if _, err := chat.Fork(atIndex, "fork"); err == nil {
+			t.Errorf("Expected an error when forking at index %d", atIndex)
+		}
+	}
+
+	for _, index := range []int{-1, 5} {
+		if err := chat.RewindTo(index); err == nil {
+			t.Errorf("Expected an error when rewinding to index %d", index)
+		}
+	}
+
+	if err := chat.RewindTo(1); err != nil {
+		t.Fatalf("RewindTo returned an error: %s", err)
+	}
+	if len(chat.Messages) != 1 || len(chat.Alternatives) != 0 {
+		t.Errorf("Expected the chat to be rewound, got %+v", chat.Messages)
+	}
+
+	if _, err := llm.Regenerate("missing", false); err == nil {
+		t.Errorf("Expected an error when regenerating a missing chat")
+	}
+}
+
+func TestChatRegenerateKeepsAlternatives(t *testing.T) {
+	llm, _ := chatServer(t)
+	chatId := "regenerate"
+
+	message := func(role, content string) Message {
+		return Message{Role: pointer(role), Content: pointer(content)}
+	}
+
+	llm.PreloadChat(Chat{
+		ID:       chatId,
+		Messages: []Message{message("user", "a"), message("assistant", "1"), message("user", "b"), message("assistant", "3")},
+		Alternatives: map[int][]Message{
+			0: {message("user", "other a")},
+			2: {message("user", "other b")},
+			3: {message("assistant", "other 3")},
+		},
+	})
+
+	if _, err := llm.Regenerate(chatId, true, llm.Chat.WithModel("llama3")); err != nil {
+		t.Fatalf("Regenerate returned an error: %s", err)
+	}
+
+	chat := mustGetChat(t, llm, chatId)
+	if len(chat.Alternatives[0]) != 1 || *chat.Alternatives[0][0].Content != "other a" {
+		t.Errorf("Expected the alternatives of the earlier messages to be kept, got %+v", chat.Alternatives[0])
+	}
+	if len(chat.Alternatives[2]) != 1 || *chat.Alternatives[2][0].Content != "other b" {
+		t.Errorf("Expected the alternatives of the user message to be kept, got %+v", chat.Alternatives[2])
+	}
+	if len(chat.Alternatives[3]) != 2 || *chat.Alternatives[3][0].Content != "other 3" || *chat.Alternatives[3][1].Content != "3" {
+		t.Errorf("Expected the old reply to be added to the alternatives, got %+v", chat.Alternatives[3])
+	}
+}
+
+func TestChatMessageAlternatives(t *testing.T) {
+	message := func(role, content string) Message {
+		return Message{Role: pointer(role), Content: pointer(content)}
+	}
+
+	chat := NewChat("alternatives", message("user", "a"), message("assistant", "1"), message("user", "b"), message("assistant", "3"))
+	chat.AddAlternative(1, message("assistant", "other 1"))
+	chat.AddAlternative(3, message("assistant", "other 3"))
+
+	// The deleted message takes its alternatives along, and the following ones move back
+	chat.DeleteMessage(1)
+	if len(chat.Alternatives) != 1 || *chat.Alternatives[2][0].Content != "other 3" {
+		t.Fatalf("Expected the alternatives to follow their messages after a delete, got %v", chat.Alternatives)
+	}
+
+	chat.AddMessageTo(0, message("system", "be nice"))
+	if len(chat.Alternatives) != 1 || *chat.Alternatives[3][0].Content != "other 3" {
+		t.Fatalf("Expected the alternatives to follow their messages after an insert, got %v", chat.Alternatives)
+	}
+
+	if err := chat.SelectAlternative(3, 0); err != nil {
+		t.Fatalf("SelectAlternative returned an error: %s", err)
+	}
+	if *chat.Messages[3].Content != "other 3" {
+		t.Errorf("Expected the reply to be swapped with its alternative, got %q", *chat.Messages[3].Content)
+	}
+}
@@ -0,0 +1,239 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ClientOption configures a client created with New.
+type ClientOption func(c *clientConfig)
+
+// DialFunc establishes a connection to the server, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// clientConfig holds the options of New.
+type clientConfig struct {
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	transport      http.RoundTripper
+	dial           DialFunc
+	socket         string // The path of the unix socket, for a unix:// URL.
+	basePath       string
+	headers        map[string][]string
+	defaults       requestDefaults
+}
+
+// requestDefaults are the values set on the chat and generate requests of a client, before the options of the request.
+type requestDefaults struct {
+	model     *string
+	options   *Options
+	keepAlive *string
+}
+
+// WithConnectTimeout limits the time spent establishing a connection to the server.
+// It requires the transport to be an *http.Transport, which is the case by default.
+//
+// Parameters:
+//   - v: The connect timeout.
+func WithConnectTimeout(v time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.connectTimeout = v
+	}
+}
+
+// WithReadTimeout limits the time spent waiting for data from the server, whether the headers of a response
+// or the next chunk of a stream. It does not limit the total duration of a request, so long generations that keep
+// streaming are not interrupted. It requires the transport to be an *http.Transport, which is the case by default.
+//
+// Parameters:
+//   - v: The read timeout.
+func WithReadTimeout(v time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.readTimeout = v
+	}
+}
+
+// WithTransport sets the transport of the HTTP client. If it is an *http.Transport and timeouts are set,
+// a clone of it is used, so the transport is not modified. Other transports cannot connect to a unix:// URL,
+// so every request of such a client fails with an error.
+//
+// Parameters:
+//   - v: The transport.
+func WithTransport(v http.RoundTripper) ClientOption {
+	return func(c *clientConfig) {
+		c.transport = v
+	}
+}
+
+// WithDialer sets the function that establishes the connections to the server, for example to tunnel them.
+// For a unix:// URL, it is called with the "unix" network and the path of the socket.
+// It requires the transport to be an *http.Transport, which is the case by default.
+//
+// Parameters:
+//   - v: The dial function.
+func WithDialer(v DialFunc) ClientOption {
+	return func(c *clientConfig) {
+		c.dial = v
+	}
+}
+
+// WithBasePath sets a path prefix for every endpoint, for an Ollama server behind a reverse proxy.
+//
+// Parameters:
+//   - v: The base path, such as "/ollama".
+func WithBasePath(v string) ClientOption {
+	return func(c *clientConfig) {
+		c.basePath = v
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request.
+//
+// Parameters:
+//   - v: The user agent.
+func WithUserAgent(v string) ClientOption {
+	return func(c *clientConfig) {
+		c.headers["User-Agent"] = []string{v}
+	}
+}
+
+// WithDefaultHeaders sets headers for every request, as SetHeaders does.
+//
+// Parameters:
+//   - v: The headers.
+func WithDefaultHeaders(v http.Header) ClientOption {
+	return func(c *clientConfig) {
+		for k, values := range v {
+			c.headers[k] = append([]string(nil), values...)
+		}
+	}
+}
+
+// WithDefaultModel sets the model of the chat and generate requests that do not set one with WithModel.
+//
+// Parameters:
+//   - v: The model name.
+func WithDefaultModel(v string) ClientOption {
+	return func(c *clientConfig) {
+		c.defaults.model = &v
+	}
+}
+
+// WithDefaultOptions sets the options of the chat and generate requests. Options set on a request, such as
+// WithTemperature, are added to the defaults, while WithOptions replaces them.
+//
+// Parameters:
+//   - v: The options.
+func WithDefaultOptions(v Options) ClientOption {
+	return func(c *clientConfig) {
+		c.defaults.options = &v
+	}
+}
+
+// WithDefaultKeepAlive sets how long the model stays loaded following the chat and generate requests
+// that do not set it with WithKeepAlive.
+//
+// Parameters:
+//   - v: The keep alive duration.
+func WithDefaultKeepAlive(v string) ClientOption {
+	return func(c *clientConfig) {
+		c.defaults.keepAlive = &v
+	}
+}
+
+// httpClient returns the HTTP client of the configuration, or an error if the transport cannot connect to the socket.
+func (c *clientConfig) httpClient() (*http.Client, error) {
+	transport := c.transport
+	if c.connectTimeout <= 0 && c.readTimeout <= 0 && c.dial == nil && len(c.socket) == 0 {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	var t *http.Transport
+	switch v := transport.(type) {
+	case nil:
+		t = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		t = v.Clone()
+	default:
+		// The connections of other transports cannot be configured
+		if len(c.socket) != 0 {
+			return &http.Client{Transport: transport}, fmt.Errorf("unix socket %s requires an *http.Transport, got a %T transport", c.socket, transport)
+		}
+		return &http.Client{Transport: transport}, nil
+	}
+
+	dial := c.dial
+	if dial == nil {
+		dial = t.DialContext
+	}
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	if socket := c.socket; len(socket) != 0 {
+		// The connections go to the socket, whatever the address of the requests
+		t.Proxy = nil
+		dialAddr := dial
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialAddr(ctx, "unix", socket)
+		}
+	}
+
+	connectTimeout, readTimeout := c.connectTimeout, c.readTimeout
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if connectTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, connectTimeout)
+			defer cancel()
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil || readTimeout <= 0 {
+			return conn, err
+		}
+		return &readTimeoutConn{Conn: conn, timeout: readTimeout}, nil
+	}
+
+	return &http.Client{Transport: t}, nil
+}
+
+// readTimeoutConn is a connection whose reads fail if no data is received within the timeout.
+type readTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *readTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// applyChat sets the defaults on a new chat request.
+func (d requestDefaults) applyChat(r *ChatRequestBuilder) {
+	r.Model = d.model
+	r.KeepAlive = d.keepAlive
+	r.Options = d.copyOptions()
+}
+
+// applyGenerate sets the defaults on a new generate request.
+func (d requestDefaults) applyGenerate(r *GenerateRequestBuilder) {
+	r.Model = d.model
+	r.KeepAlive = d.keepAlive
+	r.Options = d.copyOptions()
+}
+
+// copyOptions returns a copy of the default options, so the options of a request can be changed.
+func (d requestDefaults) copyOptions() *Options {
+	if d.options == nil {
+		return nil
+	}
+
+	v := *d.options
+	v.Stop = append([]string(nil), v.Stop...)
+	return &v
+}
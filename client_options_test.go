@@ -0,0 +1,345 @@
+package ollama
+
+import (
+	"context"
+	json2 "encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientDefaults(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/proxy/ollama/api/generate" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if ua := r.Header.Get("User-Agent"); ua != "test-agent" {
+			t.Errorf("expected user agent \"test-agent\", got %q", ua)
+		}
+		if v := r.Header.Get("X-Tenant"); v != "acme" {
+			t.Errorf("expected header X-Tenant \"acme\", got %q", v)
+		}
+
+		var body map[string]interface{}
+		json2.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		w.Write([]byte(`{"response":"ok","done":true}`))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, _ := url.Parse(server.URL + "/proxy")
+	llm := New(*uri,
+		WithBasePath("/ollama"),
+		WithUserAgent("test-agent"),
+		WithDefaultHeaders(http.Header{"X-Tenant": {"acme"}}),
+		WithDefaultModel("llama3"),
+		WithDefaultKeepAlive("1h"),
+		WithDefaultOptions(Options{Temperature: pointer(0.2), NumCtx: pointer(4096)}),
+	)
+
+	if _, err := llm.Generate(llm.Generate.WithPrompt("hi")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := llm.Generate(
+		llm.Generate.WithModel("phi3"),
+		llm.Generate.WithKeepAlive("0"),
+		llm.Generate.WithTemperature(0.7),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := llm.Generate(llm.Generate.WithOptions(Options{Seed: pointer(1)})); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+
+	first, second, third := requests[0], requests[1], requests[2]
+	if first["model"] != "llama3" || first["keep_alive"] != "1h" {
+		t.Errorf("expected the default model and keep alive, got %v", first)
+	}
+	if opts := first["options"].(map[string]interface{}); opts["temperature"] != 0.2 || opts["num_ctx"] != 4096.0 {
+		t.Errorf("expected the default options, got %v", opts)
+	}
+
+	if second["model"] != "phi3" || second["keep_alive"] != "0" {
+		t.Errorf("expected the model and keep alive of the request, got %v", second)
+	}
+	if opts := second["options"].(map[string]interface{}); opts["temperature"] != 0.7 || opts["num_ctx"] != 4096.0 {
+		t.Errorf("expected the temperature to be added to the default options, got %v", opts)
+	}
+
+	if opts := third["options"].(map[string]interface{}); opts["seed"] != 1.0 || opts["num_ctx"] != nil {
+		t.Errorf("expected WithOptions to replace the default options, got %v", opts)
+	}
+
+	// The defaults are not changed by the requests
+	if *llm.defaults.options.Temperature != 0.2 {
+		t.Errorf("expected the default temperature to be unchanged, got %v", *llm.defaults.options.Temperature)
+	}
+}
+
+func TestClientDefaultsChat(t *testing.T) {
+	var model interface{}
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json2.NewDecoder(r.Body).Decode(&body)
+		model = body["model"]
+		w.Write([]byte(`{"message":{"role":"assistant","content":"ok"},"done":true}`))
+	}, WithDefaultModel("llama3"))
+
+	if _, err := llm.Chat(nil, llm.Chat.WithMessage(Message{Role: pointer("user"), Content: pointer("hi")})); err != nil {
+		t.Fatal(err)
+	}
+
+	if model != "llama3" {
+		t.Errorf("expected the default model, got %v", model)
+	}
+}
+
+func TestClientReadTimeout(t *testing.T) {
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response":"a","done":false}` + "\n"))
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}, WithReadTimeout(100*time.Millisecond))
+
+	start := time.Now()
+	res, err := llm.Generate(llm.Generate.WithStream(true, 512000, nil))
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if res == nil || res.Response != "a" {
+		t.Errorf("expected the partial response, got %v", res)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected the read timeout to abort the stream, took %s", time.Since(start))
+	}
+}
+
+func TestClientConnectTimeout(t *testing.T) {
+	dialed := false
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = true
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	uri, _ := url.Parse("http://ollama.invalid:11434")
+	llm := New(*uri, WithTransport(transport), WithConnectTimeout(50*time.Millisecond))
+
+	_, err := llm.Models.List()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the connect timeout, got %v", err)
+	}
+	if !dialed {
+		t.Error("expected the dialer of the transport to be used")
+	}
+	if transport.DialContext == nil || llm.Http.Transport == http.RoundTripper(transport) {
+		t.Error("expected a clone of the transport to be used")
+	}
+}
+
+// newUnixSocketServer starts a fake Ollama server listening on a unix socket and returns the path of the socket.
+func newUnixSocketServer(t *testing.T, handler http.HandlerFunc) string {
+	// t.TempDir is not used, as the path of a socket is limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "ollama")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "ollama.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %s", err)
+	}
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return socket
+}
+
+func TestUnixSocket(t *testing.T) {
+	var paths []string
+	var mu sync.Mutex
+	socket := newUnixSocketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/api/chat":
+			w.Write([]byte(`{"message":{"role":"assistant","content":"Hel"},"done":false}` + "\n"))
+			w.Write([]byte(`{"message":{"role":"assistant","content":"lo"},"done":true}` + "\n"))
+		case "/api/generate":
+			w.Write([]byte(`{"response":"ok","done":true}`))
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"llama3"}]}`))
+		case "/api/blobs/sha256:abc":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	uri, _ := url.Parse("unix://" + socket)
+	llm := New(*uri)
+
+	chat, err := llm.Chat(nil,
+		llm.Chat.WithMessage(Message{Role: pointer("user"), Content: pointer("hi")}),
+		llm.Chat.WithStream(true, 512000, nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *chat.Message.Content != "Hello" {
+		t.Errorf("expected the streamed reply \"Hello\", got %q", *chat.Message.Content)
+	}
+
+	res, err := llm.Generate(llm.Generate.WithPrompt("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "ok" {
+		t.Errorf("expected the response \"ok\", got %q", res.Response)
+	}
+
+	models, err := llm.Models.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models.Models) != 1 {
+		t.Errorf("expected 1 model, got %d", len(models.Models))
+	}
+
+	if err := llm.Blobs.Check("sha256:abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"POST /api/chat", "POST /api/generate", "GET /api/tags", "HEAD /api/blobs/sha256:abc"}
+	if strings.Join(paths, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected the requests %v, got %v", expected, paths)
+	}
+}
+
+func TestUnixSocketFromEnv(t *testing.T) {
+	socket := newUnixSocketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[]}`))
+	})
+
+	t.Setenv(EnvHost, "unix://"+socket)
+	t.Setenv(EnvReadTimeout, "5s")
+
+	llm, err := NewFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := llm.Models.List(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithDialer(t *testing.T) {
+	socket := newUnixSocketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[]}`))
+	})
+
+	var dialed []string
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, network+" "+addr)
+		return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+	}
+
+	// The dialer tunnels the connections to any host
+	uri, _ := url.Parse("http://ollama.invalid:11434")
+	llm := New(*uri, WithDialer(dial))
+	if _, err := llm.Models.List(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dialed) != 1 || dialed[0] != "tcp ollama.invalid:11434" {
+		t.Errorf("expected the dialer to be called with the address of the server, got %v", dialed)
+	}
+
+	// With a unix:// URL, the dialer is called with the socket
+	dialed = nil
+	uri, _ = url.Parse("unix://" + socket)
+	llm = New(*uri, WithDialer(dial))
+	if _, err := llm.Models.List(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dialed) != 1 || dialed[0] != "unix "+socket {
+		t.Errorf("expected the dialer to be called with the socket, got %v", dialed)
+	}
+}
+
+// recordingTransport records the URLs of the requests and answers them with an empty list of models.
+type recordingTransport struct {
+	urls []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.urls = append(rt.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"models":[]}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestUnixSocketCustomTransport(t *testing.T) {
+	rt := &recordingTransport{}
+
+	uri, _ := url.Parse("unix:///var/run/ollama.sock")
+	llm := New(*uri, WithTransport(rt))
+	if _, err := llm.Models.List(); err == nil || !strings.Contains(err.Error(), "/var/run/ollama.sock") {
+		t.Errorf("expected an error for a transport that cannot connect to the socket, got %v", err)
+	}
+	if len(rt.urls) != 0 {
+		t.Errorf("expected no request to be sent, got %v", rt.urls)
+	}
+
+	t.Setenv(EnvHost, "unix:///var/run/ollama.sock")
+	if _, err := NewFromEnv(WithTransport(rt)); err == nil {
+		t.Error("expected NewFromEnv to return the error")
+	}
+
+	// Other URLs can use any transport
+	uri, _ = url.Parse("http://ollama.invalid:11434")
+	llm = New(*uri, WithTransport(rt), WithConnectTimeout(time.Second))
+	if _, err := llm.Models.List(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.urls) != 1 || rt.urls[0] != "http://ollama.invalid:11434/api/tags" {
+		t.Errorf("expected the request to be sent through the transport, got %v", rt.urls)
+	}
+}
@@ -15,9 +15,12 @@ type ModelDetails struct {
 
 // Message represents a message sent/received from the API.
 type Message struct {
-	Role    *string  `json:"role"`    // Role of the message, either system, user, or assistant.
-	Content *string  `json:"content"` // Content of the message.
-	Images  []string `json:"images"`  // Images associated with the message.
+	Role      *string    `json:"role"`                 // Role of the message, either system, user, assistant, or tool.
+	Content   *string    `json:"content"`              // Content of the message.
+	Thinking  *string    `json:"thinking,omitempty"`   // Reasoning of the model before the content, if thinking was enabled.
+	Images    []string   `json:"images"`               // Images associated with the message.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // Tools the model wants to call.
+	ToolName  *string    `json:"tool_name,omitempty"`  // Name of the called tool, for messages with the tool role.
 }
 
 // Options represents the options that will be sent to the API.
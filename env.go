@@ -0,0 +1,151 @@
+package ollama
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The environment variables read by NewFromEnv.
+const (
+	EnvHost           = "OLLAMA_HOST"            // The address of the server, as accepted by the Ollama CLI.
+	EnvAPIKey         = "OLLAMA_API_KEY"         // A key sent as a bearer token in the Authorization header.
+	EnvConnectTimeout = "OLLAMA_CONNECT_TIMEOUT" // The connect timeout, see WithConnectTimeout.
+	EnvReadTimeout    = "OLLAMA_READ_TIMEOUT"    // The read timeout, see WithReadTimeout.
+)
+
+// defaultHost is the address of the server when OLLAMA_HOST is not set.
+const defaultHost = "127.0.0.1:11434"
+
+// NewFromEnv creates a new Ollama client configured from the environment, like the Ollama CLI.
+// The server is read from OLLAMA_HOST and defaults to http://127.0.0.1:11434. As with the CLI, the scheme and the port
+// are optional, IPv6 addresses are accepted and the address 0.0.0.0 that a server listens on is mapped to localhost.
+// A unix:// URL connects through a unix domain socket.
+// OLLAMA_API_KEY sets the Authorization header, and OLLAMA_CONNECT_TIMEOUT and OLLAMA_READ_TIMEOUT set the timeouts,
+// either as a duration such as "30s" or as a number of seconds.
+// Proxies are read from HTTP_PROXY, HTTPS_PROXY and NO_PROXY, unless a transport is set with WithTransport.
+//
+// Example:
+//
+//	llm, err := ollama.NewFromEnv(ollama.WithDefaultModel("llama3"))
+//
+// Parameters:
+//   - opts: The options of the client, applied after the ones read from the environment.
+func NewFromEnv(opts ...ClientOption) (*Ollama, error) {
+	host, err := parseHost(os.Getenv(EnvHost))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", EnvHost, err)
+	}
+
+	var envOpts []ClientOption
+
+	if key := strings.TrimSpace(os.Getenv(EnvAPIKey)); len(key) != 0 {
+		envOpts = append(envOpts, WithDefaultHeaders(http.Header{"Authorization": {"Bearer " + key}}))
+	}
+
+	timeouts := []struct {
+		env    string
+		option func(time.Duration) ClientOption
+	}{
+		{EnvConnectTimeout, WithConnectTimeout},
+		{EnvReadTimeout, WithReadTimeout},
+	}
+
+	for _, t := range timeouts {
+		v := strings.TrimSpace(os.Getenv(t.env))
+		if len(v) == 0 {
+			continue
+		}
+
+		d, err := parseEnvDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.env, err)
+		}
+		envOpts = append(envOpts, t.option(d))
+	}
+
+	o := New(*host, append(envOpts, opts...)...)
+	if o.err != nil {
+		return nil, o.err
+	}
+	return o, nil
+}
+
+// parseHost parses the value of OLLAMA_HOST, following the rules of the Ollama CLI:
+//   - An empty value is the default address, http://127.0.0.1:11434.
+//   - The scheme is optional and defaults to http. Without a port, the port is 11434,
+//     or 80 and 443 when the http and https schemes are explicit.
+//   - A host without a port, a port without a host (":8080") and a path ("host/ollama") are accepted.
+//   - IPv6 addresses are written in brackets, such as "[::1]:11434". A bare address such as "::1" is also accepted.
+//   - The unspecified addresses that a server listens on, 0.0.0.0 and ::, are replaced with the loopback addresses.
+//   - A unix:// URL, such as unix:///var/run/ollama.sock, is the path of a unix domain socket.
+func parseHost(s string) (*url.URL, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		s = defaultHost
+	}
+
+	defaultPort := "11434"
+	scheme, hostport, ok := strings.Cut(s, "://")
+	switch {
+	case !ok:
+		scheme, hostport = "http", s
+	case scheme == "http":
+		defaultPort = "80"
+	case scheme == "https":
+		defaultPort = "443"
+	case scheme == "unix":
+		if len(hostport) == 0 {
+			return nil, errors.New("missing socket path")
+		}
+		return &url.URL{Scheme: scheme, Path: hostport}, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", scheme)
+	}
+
+	hostport, path, _ := strings.Cut(hostport, "/")
+
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// There is no port, or the value is a bare IPv6 address
+		host, port = strings.Trim(hostport, "[]"), defaultPort
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return nil, fmt.Errorf("invalid port %q", port)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		switch {
+		case ip.Equal(net.IPv4zero):
+			host = "127.0.0.1"
+		case ip.Equal(net.IPv6unspecified):
+			host = "::1"
+		default:
+			host = ip.String()
+		}
+	}
+	if len(host) == 0 {
+		host = "127.0.0.1"
+	}
+
+	u := &url.URL{Scheme: scheme, Host: net.JoinHostPort(host, port)}
+	if path = strings.Trim(path, "/"); len(path) != 0 {
+		u.Path = "/" + path
+	}
+	return u, nil
+}
+
+// parseEnvDuration parses a duration such as "30s", or a number of seconds.
+func parseEnvDuration(v string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(v)
+}
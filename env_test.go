@@ -0,0 +1,140 @@
+package ollama
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseHost(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "http://127.0.0.1:11434"},
+		{"   ", "http://127.0.0.1:11434"},
+		{"1.2.3.4", "http://1.2.3.4:11434"},
+		{"1.2.3.4:1234", "http://1.2.3.4:1234"},
+		{":1234", "http://127.0.0.1:1234"},
+		{"example.com", "http://example.com:11434"},
+		{"example.com:1234", "http://example.com:1234"},
+		{"http://example.com", "http://example.com:80"},
+		{"https://example.com", "https://example.com:443"},
+		{"https://example.com:1234", "https://example.com:1234"},
+		{"example.com/ollama", "http://example.com:11434/ollama"},
+		{"https://example.com/ollama/", "https://example.com:443/ollama"},
+		{"0.0.0.0", "http://127.0.0.1:11434"},
+		{"0.0.0.0:1234", "http://127.0.0.1:1234"},
+		{"[::1]", "http://[::1]:11434"},
+		{"[::1]:1234", "http://[::1]:1234"},
+		{"::1", "http://[::1]:11434"},
+		{"[::]:1234", "http://[::1]:1234"},
+		{"http://[fe80::1]:1234", "http://[fe80::1]:1234"},
+		{"unix:///var/run/ollama.sock", "unix:///var/run/ollama.sock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			u, err := parseHost(tt.value)
+			if err != nil {
+				t.Fatalf("parseHost(%q) returned an error: %s", tt.value, err)
+			}
+			if u.String() != tt.want {
+				t.Errorf("parseHost(%q) = %s, expected %s", tt.value, u, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHostErrors(t *testing.T) {
+	tests := []string{
+		"ftp://example.com",
+		"example.com:port",
+		"example.com:70000",
+		"unix://",
+	}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			if u, err := parseHost(value); err == nil {
+				t.Errorf("expected parseHost(%q) to fail, got %s", value, u)
+			}
+		})
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	var auth string
+	target := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"models":[]}`))
+	})
+
+	t.Setenv(EnvHost, target.url.Host)
+	t.Setenv(EnvAPIKey, "secret")
+	t.Setenv(EnvConnectTimeout, "5")
+	t.Setenv(EnvReadTimeout, "1m30s")
+
+	llm, err := NewFromEnv(WithUserAgent("test-agent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if llm.url.String() != target.url.String() {
+		t.Errorf("expected the URL %s, got %s", target.url.String(), llm.url.String())
+	}
+
+	if _, err := llm.Models.List(); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("expected the API key as a bearer token, got %q", auth)
+	}
+
+	if ua := llm.headers["User-Agent"]; len(ua) != 1 || ua[0] != "test-agent" {
+		t.Errorf("expected the options to be applied, got %v", ua)
+	}
+}
+
+func TestNewFromEnvErrors(t *testing.T) {
+	tests := []struct {
+		env   string
+		value string
+	}{
+		{EnvHost, "example.com:port"},
+		{EnvConnectTimeout, "soon"},
+		{EnvReadTimeout, "1 minute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			if _, err := NewFromEnv(); err == nil {
+				t.Errorf("expected %s=%q to fail", tt.env, tt.value)
+			}
+		})
+	}
+}
+
+func TestParseEnvDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"30", 30 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"2m", 2 * time.Minute},
+		{"1h30m", 90 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		d, err := parseEnvDuration(tt.value)
+		if err != nil {
+			t.Errorf("parseEnvDuration(%q) returned an error: %s", tt.value, err)
+			continue
+		}
+		if d != tt.want {
+			t.Errorf("parseEnvDuration(%q) = %s, expected %s", tt.value, d, tt.want)
+		}
+	}
+}
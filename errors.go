@@ -0,0 +1,127 @@
+package ollama
+
+import (
+	"bytes"
+	json2 "encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors that an *APIError can be matched against with errors.Is.
+var (
+	ErrBadRequest       = errors.New("bad request")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrNotFound         = errors.New("not found")
+	ErrModelNotFound    = errors.New("model not found")
+	ErrServerOverloaded = errors.New("server overloaded")
+)
+
+// ErrStopStream can be returned from a stream handler to stop the stream early.
+// The connection is closed and the function returns the response received so far without an error.
+var ErrStopStream = errors.New("stop stream")
+
+// ErrUnknownTool is returned by ToolRegistry.Call when the model calls a tool that is not registered.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// ErrToolCallDenied is reported to the model when a tool call is not approved by AgentOptions.Approve.
+var ErrToolCallDenied = errors.New("tool call denied")
+
+// ErrMaxIterations is returned by RunAgent when the model still calls tools after the maximum number of iterations.
+var ErrMaxIterations = errors.New("agent: maximum number of iterations reached")
+
+// APIError is returned when the Ollama API responds with an error.
+//
+// Use errors.Is with the sentinel errors of this package to check for a specific failure:
+//
+//	if errors.Is(err, ollama.ErrModelNotFound) {
+//		// pull the model
+//	}
+type APIError struct {
+	StatusCode int    // The HTTP status code of the response.
+	Message    string // The message of the {"error": "..."} body, if any.
+	Method     string // The HTTP method of the request.
+	Path       string // The API path of the request.
+	Body       []byte // The raw response body.
+
+	RetryAfter time.Duration // The delay requested by the Retry-After header of the response, if any.
+}
+
+func newAPIError(method, path string, statusCode int, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: statusCode,
+		Method:     method,
+		Path:       path,
+		Body:       body,
+	}
+
+	var parsed struct {
+		Error string `json:"error"`
+	}
+	if err := json2.Unmarshal(body, &parsed); err == nil {
+		e.Message = parsed.Error
+	}
+
+	return e
+}
+
+// newStreamError returns an *APIError if the streamed chunk is an {"error": "..."} frame, or nil otherwise.
+// Ollama reports failures that happen after the response has started this way, with a successful status code.
+func newStreamError(method, path string, statusCode int, chunk []byte) *APIError {
+	if !bytes.Contains(chunk, []byte(`"error"`)) {
+		return nil
+	}
+
+	e := newAPIError(method, path, statusCode, chunk)
+	if len(e.Message) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// errorMessage returns the message of an *APIError, or the error string for any other error.
+func errorMessage(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && len(apiErr.Message) != 0 {
+		return apiErr.Message
+	}
+
+	return err.Error()
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if len(msg) == 0 {
+		msg = strings.TrimSpace(string(e.Body))
+	}
+	if len(msg) == 0 {
+		msg = http.StatusText(e.StatusCode)
+	}
+
+	return fmt.Sprintf("%s %s: status code: %d, error: %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+// Is reports whether the error matches one of the sentinel errors of this package.
+func (e *APIError) Is(target error) bool {
+	msg := strings.ToLower(e.Message)
+
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrModelNotFound:
+		return strings.Contains(msg, "model") &&
+			(e.StatusCode == http.StatusNotFound || strings.Contains(msg, "not found") || strings.Contains(msg, "does not exist"))
+	case ErrServerOverloaded:
+		return e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusTooManyRequests ||
+			strings.Contains(msg, "server busy")
+	}
+
+	return false
+}
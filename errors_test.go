@@ -0,0 +1,72 @@
+package ollama
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model 'llama3' not found, try pulling it first"}`))
+	})
+
+	_, err := llm.Generate(llm.Generate.WithModel("llama3"))
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got %v", err)
+	}
+
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Path != "/api/generate" || apiErr.Method != http.MethodPost {
+		t.Errorf("Unexpected APIError fields: %+v", apiErr)
+	}
+
+	if apiErr.Message != "model 'llama3' not found, try pulling it first" {
+		t.Errorf("Unexpected message: %s", apiErr.Message)
+	}
+
+	if !errors.Is(err, ErrModelNotFound) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected error to match ErrModelNotFound and ErrNotFound")
+	}
+
+	if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrServerOverloaded) {
+		t.Errorf("Expected error to not match ErrUnauthorized or ErrServerOverloaded")
+	}
+}
+
+func TestStreamErrorFrame(t *testing.T) {
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"pulling manifest\"}\n{\"error\":\"pull model manifest: file does not exist\"}\n{\"status\":\"success\"}\n"))
+	})
+
+	var streamErr error
+	chunks := 0
+	res, err := llm.Models.Pull(
+		llm.Models.Pull.WithModel("missing"),
+		llm.Models.Pull.WithStream(true, 512000, func(r *PushPullModelResponse, err error) {
+			if err != nil {
+				streamErr = err
+			} else {
+				chunks++
+			}
+		}),
+	)
+
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("Expected ErrModelNotFound, got %v", err)
+	}
+
+	if streamErr != err {
+		t.Errorf("Expected the stream function to receive the error, got %v", streamErr)
+	}
+
+	if chunks != 1 {
+		t.Errorf("Expected 1 chunk before the error, got %d", chunks)
+	}
+
+	if res == nil || res.Status != "pulling manifest\n" || res.Error != "pull model manifest: file does not exist" {
+		t.Errorf("Unexpected partial response: %+v", res)
+	}
+}
@@ -1,21 +1,30 @@
 package ollama
 
 import (
-	"bytes"
 	json2 "encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 )
 
 // ChatFunc performs a request to the Ollama API with the provided instructions.
-// If chatId is set, it will append the messages from previous requests to the current request.
+// If chatId is set, it will include the messages from previous requests before the current messages,
+// and store the current messages along with the reply once the request succeeds.
 // If chatId is not found, a new chat will be generated.
 //
 // For more information about the request, see the API documentation:
 // https://github.com/ollama/ollama/blob/main/docs/api.md
 type ChatFunc func(chatId *string, builder ...func(reqBuilder *ChatRequestBuilder)) (*ChatResponse, error)
 
+// ChatStreamFunc performs a streaming request to the Ollama API with the provided instructions
+// and returns a Stream of the responses. The chat history is handled the same way as in ChatFunc;
+// the reply is added to the chat once the stream is completed.
+//
+// For more information about the request, see the API documentation:
+// https://github.com/ollama/ollama/blob/main/docs/api.md
+type ChatStreamFunc func(chatId *string, builder ...func(reqBuilder *ChatRequestBuilder)) (*Stream[ChatResponse], error)
+
 // GenerateFunc performs a request to the Ollama API with the provided instructions.
 // If the prompt is not set, the model will be loaded into memory.
 //
@@ -23,6 +32,13 @@ type ChatFunc func(chatId *string, builder ...func(reqBuilder *ChatRequestBuilde
 // https://github.com/ollama/ollama/blob/main/docs/api.md
 type GenerateFunc func(builder ...func(reqBuilder *GenerateRequestBuilder)) (*GenerateResponse, error)
 
+// GenerateStreamFunc performs a streaming request to the Ollama API with the provided instructions
+// and returns a Stream of the responses.
+//
+// For more information about the request, see the API documentation:
+// https://github.com/ollama/ollama/blob/main/docs/api.md
+type GenerateStreamFunc func(builder ...func(reqBuilder *GenerateRequestBuilder)) (*Stream[GenerateResponse], error)
+
 // BlobCreateFunc performs a request to the Ollama API to create a new blob with the provided blob file.
 //
 // For more information about the request, see the API documentation:
@@ -42,6 +58,13 @@ type BlobCheckFunc func(digest string) error
 // https://github.com/ollama/ollama/blob/main/docs/api.md
 type CreateModelFunc func(builder ...func(modelFileBuilder *ModelFileRequestBuilder)) (*StatusResponse, error)
 
+// CreateModelStreamFunc performs a request to the Ollama API to create a new model with the provided model file
+// and returns a Stream of the status updates.
+//
+// For more information about the request, see the API documentation:
+// https://github.com/ollama/ollama/blob/main/docs/api.md
+type CreateModelStreamFunc func(builder ...func(modelFileBuilder *ModelFileRequestBuilder)) (*Stream[StatusResponse], error)
+
 // ListLocalModelsFunc performs a request to the Ollama API to retrieve the local models.
 //
 // For more information about the request, see the API documentation:
@@ -72,6 +95,13 @@ type DeleteModelFunc func(name string) error
 // https://github.com/ollama/ollama/blob/main/docs/api.md
 type PullModelFunc func(...func(modelFileBuilder *PullModelRequestBuilder)) (*PushPullModelResponse, error)
 
+// PullModelStreamFunc performs a request to the Ollama API to pull model from the ollama library
+// and returns a Stream of the progress updates.
+//
+// For more information about the request, see the API documentation:
+// https://github.com/ollama/ollama/blob/main/docs/api.md
+type PullModelStreamFunc func(...func(modelFileBuilder *PullModelRequestBuilder)) (*Stream[PushPullModelResponse], error)
+
 // PushModelFunc performs a request to the Ollama API to push model to the ollama library.
 // Requires registering for ollama.ai and adding a public key first
 //
@@ -79,6 +109,13 @@ type PullModelFunc func(...func(modelFileBuilder *PullModelRequestBuilder)) (*Pu
 // https://github.com/ollama/ollama/blob/main/docs/api.md
 type PushModelFunc func(...func(modelFileBuilder *PushModelRequestBuilder)) (*PushPullModelResponse, error)
 
+// PushModelStreamFunc performs a request to the Ollama API to push model to the ollama library
+// and returns a Stream of the progress updates.
+//
+// For more information about the request, see the API documentation:
+// https://github.com/ollama/ollama/blob/main/docs/api.md
+type PushModelStreamFunc func(...func(modelFileBuilder *PushModelRequestBuilder)) (*Stream[PushPullModelResponse], error)
+
 // GenerateEmbeddingsFunc performs a request to the Ollama API to generate embeddings from a model.
 //
 // For more information about the request, see the API documentation:
@@ -94,6 +131,7 @@ type VersionFunc func() (*VersionResponse, error)
 func (o *Ollama) newChatFunc() ChatFunc {
 	return func(chatId *string, builder ...func(reqBuilder *ChatRequestBuilder)) (*ChatResponse, error) {
 		req := ChatRequestBuilder{}
+		o.defaults.applyChat(&req)
 		for _, f := range builder {
 			f(&req)
 		}
@@ -106,31 +144,43 @@ func (o *Ollama) newChatFunc() ChatFunc {
 			req.StreamBufferSize = pointer(512000)
 		}
 
-		// Include chat history or create a new chat
-		if chatId != nil {
-			chat := o.chats[*chatId]
-			if chat == nil {
-				chat = &Chat{
-					ID:       *chatId,
-					Messages: make([]Message, 0),
-				}
-				o.chats[*chatId] = chat
-			}
+		return o.chatTurn(chatId, &req, o.sendChat)
+	}
+}
 
-			for _, chat := range chat.Messages {
-				req.Messages = append([]Message{chat}, req.Messages...)
-			}
+// chatTurn includes the history of the chat in the request, sends it with send and, once it succeeds,
+// stores the messages of the request along with the reply. If chatId is nil, the request is only sent.
+func (o *Ollama) chatTurn(chatId *string, req *ChatRequestBuilder, send func(req *ChatRequestBuilder) (*ChatResponse, error)) (*ChatResponse, error) {
+	var turn []Message
+	if chatId != nil {
+		unlock := o.turns.Lock(*chatId)
+		defer unlock()
+
+		var err error
+		if turn, err = o.includeChatHistory(*chatId, req); err != nil {
+			return nil, err
 		}
+	}
 
-		var stream func(b []byte)
-		if req.StreamFunc != nil {
-			stream = func(b []byte) {
-				req.StreamFunc(bodyTo[ChatResponse](b))
-			}
+	final, err := send(req)
+	if err != nil {
+		return final, err
+	}
+
+	if chatId != nil {
+		if err := o.chats.Append(*chatId, append(turn, storedReply(req, final.Message))...); err != nil {
+			return final, err
 		}
+	}
 
-		body, err := o.stream(http.MethodPost, "/api/chat", req, *req.StreamBufferSize, stream)
-		if err != nil {
+	return final, nil
+}
+
+// sendChat performs the chat request and connects the responses into a single response.
+func (o *Ollama) sendChat(req *ChatRequestBuilder) (*ChatResponse, error) {
+	return invoke(o, http.MethodPost, "/api/chat", req, func(o *Ollama) (*ChatResponse, error) {
+		body, err := o.stream(o.ctx, http.MethodPost, "/api/chat", req, *req.StreamBufferSize, streamFuncOf(req.StreamFunc, req.StreamHandler))
+		if err != nil && len(body) == 0 {
 			return nil, err
 		}
 
@@ -143,49 +193,199 @@ func (o *Ollama) newChatFunc() ChatFunc {
 			resp = append(resp, *r)
 		}
 
-		// Connect types
-		final := &ChatResponse{}
-		for i, r := range resp {
-			if i == 0 {
-				final.Model = r.Model
-				final.CreatedAt = r.CreatedAt
-				final.Message = Message{
-					Role:    r.Message.Role,
-					Content: pointer(""),
-				}
-				final.Done = r.Done
+		final := mergeChatResponses(resp)
+
+		if splitsThinking(req.Think) {
+			final.Message = splitMessageThinking(final.Message)
+		}
+
+		if errors.Is(err, ErrStopStream) {
+			final.Done = true
+			final.DoneReason = DoneReasonClientAbort
+			err = nil
+		}
+
+		return final, err
+	})
+}
+
+func (o *Ollama) newChatStreamFunc() ChatStreamFunc {
+	return func(chatId *string, builder ...func(reqBuilder *ChatRequestBuilder)) (*Stream[ChatResponse], error) {
+		req := ChatRequestBuilder{}
+		o.defaults.applyChat(&req)
+		for _, f := range builder {
+			f(&req)
+		}
+
+		req.Stream = pointer(true)
+
+		if req.StreamBufferSize == nil {
+			req.StreamBufferSize = pointer(512000)
+		}
+
+		if chatId == nil {
+			return o.openChatStream(&req)
+		}
+
+		// The chat is locked until the stream is closed
+		unlock := o.turns.Lock(*chatId)
+		turn, err := o.includeChatHistory(*chatId, &req)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+
+		s, err := o.openChatStream(&req)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+
+		resp := make([]ChatResponse, 0)
+		s.onChunk = func(r *ChatResponse) {
+			resp = append(resp, *r)
+		}
+		s.onEnd = func(err error) error {
+			if err != nil {
+				return err
+			}
+			final := mergeChatResponses(resp)
+			if splitsThinking(req.Think) {
+				final.Message = splitMessageThinking(final.Message)
 			}
+			return o.chats.Append(*chatId, append(turn, storedReply(&req, final.Message))...)
+		}
+		s.onClose = unlock
+
+		return s, nil
+	}
+}
+
+// openChatStream opens the stream of a chat request.
+func (o *Ollama) openChatStream(req *ChatRequestBuilder) (*Stream[ChatResponse], error) {
+	return invoke(o, http.MethodPost, "/api/chat", req, func(o *Ollama) (*Stream[ChatResponse], error) {
+		return openStream[ChatResponse](o, o.ctx, http.MethodPost, "/api/chat", req, *req.StreamBufferSize)
+	})
+}
+
+// includeChatHistory prepends the messages of the chat to the request, in chronological order,
+// and returns the messages of the new turn. The turn is stored along with the reply once the request succeeds.
+func (o *Ollama) includeChatHistory(chatId string, req *ChatRequestBuilder) ([]Message, error) {
+	turn := append(make([]Message, 0, len(req.Messages)+1), req.Messages...)
 
-			if r.Message.Content != nil {
-				final.Message.Content = pointer(*final.Message.Content + *r.Message.Content)
+	chat, err := o.chats.Get(chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat == nil {
+		return turn, nil
+	}
+
+	if req.KeepThinking == nil {
+		req.KeepThinking = pointer(chat.KeepThinking)
+	}
+
+	summaryPolicy := req.SummaryPolicy
+	if summaryPolicy == nil {
+		summaryPolicy = chat.SummaryPolicy
+	}
+
+	if summaryPolicy != nil {
+		changed, err := o.summarize(summaryPolicy, chat, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			if err := o.chats.Put(chat); err != nil {
+				return nil, err
 			}
+		}
+	}
+
+	req.Messages = append(o.chatHistory(chat, turn, req), turn...)
+	return turn, nil
+}
+
+// chatHistory returns the messages of the chat that are sent along with the turn,
+// including the summary of the archived messages, according to the history policy.
+func (o *Ollama) chatHistory(chat *Chat, turn []Message, req *ChatRequestBuilder) []Message {
+	summaryPolicy := req.SummaryPolicy
+	if summaryPolicy == nil {
+		summaryPolicy = chat.SummaryPolicy
+	}
+
+	history := chat.GetMessages()
+
+	policy := req.HistoryPolicy
+	if policy == nil {
+		policy = chat.HistoryPolicy
+	}
+
+	// The summary is always sent, so it counts towards the budget like the turn
+	var summary []Message
+	if len(chat.Summary) != 0 {
+		summary = append(summary, summaryMessage(summaryPolicy, chat.Summary))
+	}
+
+	if policy != nil {
+		history = policy.apply(history, append(summary, turn...), o.historyBudget(policy, req))
+	}
+
+	if len(summary) != 0 {
+		history = withSummary(history, summary[0])
+	}
+
+	return history
+}
 
-			if r.Message.Images != nil && len(r.Message.Images) > 0 {
-				final.Message.Images = append(final.Message.Images, r.Message.Images...)
+// mergeChatResponses connects the streamed responses into a single response with the concatenated message.
+func mergeChatResponses(resp []ChatResponse) *ChatResponse {
+	final := &ChatResponse{}
+	for i, r := range resp {
+		if i == 0 {
+			final.Model = r.Model
+			final.CreatedAt = r.CreatedAt
+			final.Message = Message{
+				Role:    r.Message.Role,
+				Content: pointer(""),
 			}
+		}
+
+		if r.Message.Content != nil {
+			final.Message.Content = pointer(*final.Message.Content + *r.Message.Content)
+		}
 
-			if i == len(resp)-1 {
-				final.TotalDuration = r.TotalDuration
-				final.LoadDuration = r.LoadDuration
-				final.PromptEvalCount = r.PromptEvalCount
-				final.PromptEvalDuration = r.PromptEvalDuration
-				final.EvalCount = r.EvalCount
-				final.EvalDuration = r.EvalDuration
-				final.Context = r.Context
+		if r.Message.Thinking != nil {
+			if final.Message.Thinking == nil {
+				final.Message.Thinking = pointer("")
 			}
+			final.Message.Thinking = pointer(*final.Message.Thinking + *r.Message.Thinking)
 		}
 
-		if chatId != nil {
-			o.chats[*chatId].AddMessage(final.Message)
+		if r.Message.Images != nil && len(r.Message.Images) > 0 {
+			final.Message.Images = append(final.Message.Images, r.Message.Images...)
 		}
 
-		return final, nil
+		// Each chunk carries complete tool calls
+		final.Message.ToolCalls = append(final.Message.ToolCalls, r.Message.ToolCalls...)
+
+		if i == len(resp)-1 {
+			final.Done = r.Done
+			final.DoneReason = r.DoneReason
+			final.Metrics = r.Metrics
+			final.Context = r.Context
+		}
 	}
+
+	return final
 }
 
 func (o *Ollama) newGenerateFunc() GenerateFunc {
 	return func(builder ...func(reqBuilder *GenerateRequestBuilder)) (*GenerateResponse, error) {
 		req := GenerateRequestBuilder{}
+		o.defaults.applyGenerate(&req)
 		for _, f := range builder {
 			f(&req)
 		}
@@ -198,74 +398,105 @@ func (o *Ollama) newGenerateFunc() GenerateFunc {
 			req.StreamBufferSize = pointer(512000)
 		}
 
-		var stream func(b []byte)
-		if req.StreamFunc != nil {
-			stream = func(b []byte) {
-				req.StreamFunc(bodyTo[GenerateResponse](b))
+		return invoke(o, http.MethodPost, "/api/generate", &req, func(o *Ollama) (*GenerateResponse, error) {
+			body, err := o.stream(o.ctx, http.MethodPost, "/api/generate", req, *req.StreamBufferSize, streamFuncOf(req.StreamFunc, req.StreamHandler))
+			if err != nil && len(body) == 0 {
+				return nil, err
 			}
-		}
 
-		body, err := o.stream(http.MethodPost, "/api/generate", req, *req.StreamBufferSize, stream)
-		if err != nil {
-			return nil, err
-		}
+			resp := make([]GenerateResponse, 0)
+			for _, b := range body {
+				r, err := bodyTo[GenerateResponse](b)
+				if err != nil {
+					return nil, err
+				}
+				resp = append(resp, *r)
+			}
 
-		resp := make([]GenerateResponse, 0)
-		for _, b := range body {
-			r, err := bodyTo[GenerateResponse](b)
-			if err != nil {
-				return nil, err
+			final := mergeGenerateResponses(resp)
+
+			if splitsThinking(req.Think) && len(final.Thinking) == 0 {
+				final.Thinking, final.Response = SplitThinking(final.Response)
 			}
-			resp = append(resp, *r)
-		}
 
-		// Connect types
-		final := &GenerateResponse{}
-		for i, r := range resp {
-			if i == 0 {
-				final.Model = r.Model
-				final.CreatedAt = r.CreatedAt
-				final.Done = r.Done
+			if errors.Is(err, ErrStopStream) {
+				final.Done = true
+				final.DoneReason = DoneReasonClientAbort
+				err = nil
 			}
 
-			final.Response += r.Response
+			return final, err
+		})
+	}
+}
 
-			if i == len(resp)-1 {
-				final.TotalDuration = r.TotalDuration
-				final.LoadDuration = r.LoadDuration
-				final.PromptEvalCount = r.PromptEvalCount
-				final.PromptEvalDuration = r.PromptEvalDuration
-				final.EvalCount = r.EvalCount
-				final.EvalDuration = r.EvalDuration
-				final.Context = r.Context
-			}
+func (o *Ollama) newGenerateStreamFunc() GenerateStreamFunc {
+	return func(builder ...func(reqBuilder *GenerateRequestBuilder)) (*Stream[GenerateResponse], error) {
+		req := GenerateRequestBuilder{}
+		o.defaults.applyGenerate(&req)
+		for _, f := range builder {
+			f(&req)
+		}
+
+		req.Stream = pointer(true)
+
+		if req.StreamBufferSize == nil {
+			req.StreamBufferSize = pointer(512000)
 		}
 
-		return final, nil
+		return invoke(o, http.MethodPost, "/api/generate", &req, func(o *Ollama) (*Stream[GenerateResponse], error) {
+			return openStream[GenerateResponse](o, o.ctx, http.MethodPost, "/api/generate", req, *req.StreamBufferSize)
+		})
 	}
 }
 
+// mergeGenerateResponses connects the streamed responses into a single response with the concatenated text.
+func mergeGenerateResponses(resp []GenerateResponse) *GenerateResponse {
+	final := &GenerateResponse{}
+	for i, r := range resp {
+		if i == 0 {
+			final.Model = r.Model
+			final.CreatedAt = r.CreatedAt
+		}
+
+		final.Response += r.Response
+		final.Thinking += r.Thinking
+
+		if i == len(resp)-1 {
+			final.Done = r.Done
+			final.DoneReason = r.DoneReason
+			final.Metrics = r.Metrics
+			final.Context = r.Context
+		}
+	}
+
+	return final
+}
+
 func (o *Ollama) newBlobCreateFunc() BlobCreateFunc {
 	return func(digest string, data []byte) error {
-		res, err := o.request(http.MethodPost, "/api/blobs/"+digest, bytes.NewBuffer(data))
-		if err != nil {
-			return err
-		}
-		defer res.Body.Close()
+		req := &BlobRequest{Digest: digest, Data: data}
+		_, err := invoke(o, http.MethodPost, "/api/blobs/"+digest, req, func(o *Ollama) (*struct{}, error) {
+			res, err := o.request(o.ctx, http.MethodPost, "/api/blobs/"+req.Digest, req.Data)
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
 
-		return nil
+			return nil, nil
+		})
+		return err
 	}
 }
 
 func (o *Ollama) newBlobCheckFunc() BlobCheckFunc {
 	return func(digest string) error {
-		res, err := o.request(http.MethodHead, "/api/blobs/"+digest, nil)
-		if err != nil {
-			return err
-		}
-		defer res.Body.Close()
-
-		return nil
+		req := &BlobRequest{Digest: digest}
+		_, err := invoke(o, http.MethodHead, "/api/blobs/"+digest, req, func(o *Ollama) (*struct{}, error) {
+			_, err := o.requestWithRetry(o.ctx, http.MethodHead, "/api/blobs/"+req.Digest, nil)
+			return nil, err
+		})
+		return err
 	}
 }
 
@@ -280,52 +511,72 @@ func (o *Ollama) newCreateModelFunc() CreateModelFunc {
 			req.StreamBufferSize = pointer(512000)
 		}
 
-		var stream func(b []byte)
-		if req.StreamFunc != nil {
-			stream = func(b []byte) {
-				req.StreamFunc(bodyTo[StatusResponse](b))
+		req.Modelfile = pointer(req.Build())
+
+		return invoke(o, http.MethodPost, "/api/create", &req, func(o *Ollama) (*StatusResponse, error) {
+			body, err := o.stream(o.ctx, http.MethodPost, "/api/create", req, *req.StreamBufferSize, streamFuncOf(req.StreamFunc, req.StreamHandler))
+			if err != nil && len(body) == 0 {
+				return nil, err
 			}
-		}
 
-		req.Modelfile = pointer(req.Build())
+			resp := make([]StatusResponse, 0)
+			for _, b := range body {
+				r, err := bodyTo[StatusResponse](b)
+				if err != nil {
+					return nil, err
+				}
+				resp = append(resp, *r)
+			}
 
-		body, err := o.stream(http.MethodPost, "/api/create", req, *req.StreamBufferSize, stream)
-		if err != nil {
-			return nil, err
-		}
+			final := &StatusResponse{}
+			for _, r := range resp {
+				final.Status += r.Status + "\n"
+			}
+
+			if errors.Is(err, ErrStopStream) {
+				err = nil
+			}
 
-		resp := make([]StatusResponse, 0)
-		for _, b := range body {
-			r, err := bodyTo[StatusResponse](b)
 			if err != nil {
-				return nil, err
+				final.Error = errorMessage(err)
 			}
-			resp = append(resp, *r)
+
+			return final, err
+		})
+	}
+}
+
+func (o *Ollama) newCreateModelStreamFunc() CreateModelStreamFunc {
+	return func(builder ...func(modelFileBuilder *ModelFileRequestBuilder)) (*Stream[StatusResponse], error) {
+		req := ModelFileRequestBuilder{}
+		for _, f := range builder {
+			f(&req)
 		}
 
-		final := &StatusResponse{}
-		for _, r := range resp {
-			final.Status += r.Status + "\n"
+		req.Stream = pointer(true)
+
+		if req.StreamBufferSize == nil {
+			req.StreamBufferSize = pointer(512000)
 		}
 
-		return final, nil
+		req.Modelfile = pointer(req.Build())
+
+		return invoke(o, http.MethodPost, "/api/create", &req, func(o *Ollama) (*Stream[StatusResponse], error) {
+			return openStream[StatusResponse](o, o.ctx, http.MethodPost, "/api/create", req, *req.StreamBufferSize)
+		})
 	}
 }
 
 func (o *Ollama) newListLocalModelsFunc() ListLocalModelsFunc {
 	return func() (*ListLocalModelsResponse, error) {
-		res, err := o.request(http.MethodGet, "/api/tags", nil)
-		if err != nil {
-			return nil, err
-		}
-		defer res.Body.Close()
-
-		body, err := io.ReadAll(res.Body)
-		if err != nil {
-			return nil, err
-		}
+		return invoke(o, http.MethodGet, "/api/tags", nil, func(o *Ollama) (*ListLocalModelsResponse, error) {
+			body, err := o.requestWithRetry(o.ctx, http.MethodGet, "/api/tags", nil)
+			if err != nil {
+				return nil, err
+			}
 
-		return bodyTo[ListLocalModelsResponse](body)
+			return bodyTo[ListLocalModelsResponse](body)
+		})
 	}
 }
 
@@ -336,62 +587,61 @@ func (o *Ollama) newShowModelInfoFunc() ShowModelInfoFunc {
 			f(&req)
 		}
 
-		json, err := json2.Marshal(req)
-		if err != nil {
-			return nil, err
-		}
-
-		res, err := o.request(http.MethodPost, "/api/show", bytes.NewBuffer(json))
-		if err != nil {
-			return nil, err
-		}
-		defer res.Body.Close()
+		return invoke(o, http.MethodPost, "/api/show", &req, func(o *Ollama) (*ShowModelInfoResponse, error) {
+			json, err := json2.Marshal(req)
+			if err != nil {
+				return nil, err
+			}
 
-		body, err := io.ReadAll(res.Body)
-		if err != nil {
-			return nil, err
-		}
+			body, err := o.requestWithRetry(o.ctx, http.MethodPost, "/api/show", json)
+			if err != nil {
+				return nil, err
+			}
 
-		return bodyTo[ShowModelInfoResponse](body)
+			return bodyTo[ShowModelInfoResponse](body)
+		})
 	}
 }
 
 func (o *Ollama) newCopyModelFunc() CopyModelFunc {
 	return func(source, destination string) error {
-		json, err := json2.Marshal(map[string]string{
-			"source":      source,
-			"destination": destination,
-		})
-		if err != nil {
-			return err
-		}
+		req := &CopyModelRequest{Source: source, Destination: destination}
+		_, err := invoke(o, http.MethodPost, "/api/copy", req, func(o *Ollama) (*struct{}, error) {
+			json, err := json2.Marshal(req)
+			if err != nil {
+				return nil, err
+			}
 
-		res, err := o.request(http.MethodPost, "/api/copy", bytes.NewBuffer(json))
-		if err != nil {
-			return err
-		}
-		defer res.Body.Close()
+			res, err := o.request(o.ctx, http.MethodPost, "/api/copy", json)
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
 
-		return nil
+			return nil, nil
+		})
+		return err
 	}
 }
 
 func (o *Ollama) newDeleteModelFunc() DeleteModelFunc {
 	return func(model string) error {
-		json, err := json2.Marshal(map[string]string{
-			"model": model,
-		})
-		if err != nil {
-			return err
-		}
+		req := &DeleteModelRequest{Model: model}
+		_, err := invoke(o, http.MethodDelete, "/api/delete", req, func(o *Ollama) (*struct{}, error) {
+			json, err := json2.Marshal(req)
+			if err != nil {
+				return nil, err
+			}
 
-		res, err := o.request(http.MethodDelete, "/api/delete", bytes.NewBuffer(json))
-		if err != nil {
-			return err
-		}
-		defer res.Body.Close()
+			res, err := o.request(o.ctx, http.MethodDelete, "/api/delete", json)
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
 
-		return nil
+			return nil, nil
+		})
+		return err
 	}
 }
 
@@ -406,39 +656,57 @@ func (o *Ollama) newPullModelFunc() PullModelFunc {
 			req.StreamBufferSize = pointer(512000)
 		}
 
-		var stream func(b []byte)
-		if req.StreamFunc != nil {
-			stream = func(b []byte) {
-				req.StreamFunc(bodyTo[PushPullModelResponse](b))
+		return invoke(o, http.MethodPost, "/api/pull", &req, func(o *Ollama) (*PushPullModelResponse, error) {
+			body, err := o.stream(o.ctx, http.MethodPost, "/api/pull", req, *req.StreamBufferSize, streamFuncOf(req.StreamFunc, req.StreamHandler))
+			if err != nil && len(body) == 0 {
+				return nil, err
 			}
-		}
 
-		body, err := o.stream(http.MethodPost, "/api/pull", req, *req.StreamBufferSize, stream)
-		if err != nil {
-			return nil, err
-		}
+			resp := make([]PushPullModelResponse, 0)
+			for _, b := range body {
+				r, err := bodyTo[PushPullModelResponse](b)
+				if err != nil {
+					return nil, err
+				}
+				resp = append(resp, *r)
+			}
 
-		resp := make([]PushPullModelResponse, 0)
-		for _, b := range body {
-			r, err := bodyTo[PushPullModelResponse](b)
-			if err != nil {
-				return nil, err
+			final := &PushPullModelResponse{}
+			for _, r := range resp {
+				if len(r.Status) != 0 {
+					final.Status += r.Status + "\n"
+				}
 			}
-			resp = append(resp, *r)
-		}
 
-		final := &PushPullModelResponse{}
-		for _, r := range resp {
-			if len(r.Status) != 0 {
-				final.Status += r.Status + "\n"
+			if errors.Is(err, ErrStopStream) {
+				err = nil
 			}
 
-			if len(r.Error) != 0 {
-				final.Error += r.Error + "\n"
+			if err != nil {
+				final.Error = errorMessage(err)
 			}
+
+			return final, err
+		})
+	}
+}
+
+func (o *Ollama) newPullModelStreamFunc() PullModelStreamFunc {
+	return func(builder ...func(modelFileBuilder *PullModelRequestBuilder)) (*Stream[PushPullModelResponse], error) {
+		req := PullModelRequestBuilder{}
+		for _, f := range builder {
+			f(&req)
 		}
 
-		return final, nil
+		req.Stream = pointer(true)
+
+		if req.StreamBufferSize == nil {
+			req.StreamBufferSize = pointer(512000)
+		}
+
+		return invoke(o, http.MethodPost, "/api/pull", &req, func(o *Ollama) (*Stream[PushPullModelResponse], error) {
+			return openStream[PushPullModelResponse](o, o.ctx, http.MethodPost, "/api/pull", req, *req.StreamBufferSize)
+		})
 	}
 }
 
@@ -453,33 +721,55 @@ func (o *Ollama) newPushModelFunc() PushModelFunc {
 			req.StreamBufferSize = pointer(512000)
 		}
 
-		var stream func(b []byte)
-		if req.StreamFunc != nil {
-			stream = func(b []byte) {
-				req.StreamFunc(bodyTo[PushPullModelResponse](b))
+		return invoke(o, http.MethodPost, "/api/push", &req, func(o *Ollama) (*PushPullModelResponse, error) {
+			body, err := o.stream(o.ctx, http.MethodPost, "/api/push", req, *req.StreamBufferSize, streamFuncOf(req.StreamFunc, req.StreamHandler))
+			if err != nil && len(body) == 0 {
+				return nil, err
 			}
-		}
 
-		body, err := o.stream(http.MethodPost, "/api/push", req, *req.StreamBufferSize, stream)
-		if err != nil {
-			return nil, err
-		}
+			resp := make([]PushPullModelResponse, 0)
+			for _, b := range body {
+				r, err := bodyTo[PushPullModelResponse](b)
+				if err != nil {
+					return nil, err
+				}
+				resp = append(resp, *r)
+			}
+
+			final := &PushPullModelResponse{}
+			for _, r := range resp {
+				final.Status += r.Status + "\n"
+			}
+
+			if errors.Is(err, ErrStopStream) {
+				err = nil
+			}
 
-		resp := make([]PushPullModelResponse, 0)
-		for _, b := range body {
-			r, err := bodyTo[PushPullModelResponse](b)
 			if err != nil {
-				return nil, err
+				final.Error = errorMessage(err)
 			}
-			resp = append(resp, *r)
+
+			return final, err
+		})
+	}
+}
+
+func (o *Ollama) newPushModelStreamFunc() PushModelStreamFunc {
+	return func(builder ...func(modelFileBuilder *PushModelRequestBuilder)) (*Stream[PushPullModelResponse], error) {
+		req := PushModelRequestBuilder{}
+		for _, f := range builder {
+			f(&req)
 		}
 
-		final := &PushPullModelResponse{}
-		for _, r := range resp {
-			final.Status += r.Status + "\n"
+		req.Stream = pointer(true)
+
+		if req.StreamBufferSize == nil {
+			req.StreamBufferSize = pointer(512000)
 		}
 
-		return final, nil
+		return invoke(o, http.MethodPost, "/api/push", &req, func(o *Ollama) (*Stream[PushPullModelResponse], error) {
+			return openStream[PushPullModelResponse](o, o.ctx, http.MethodPost, "/api/push", req, *req.StreamBufferSize)
+		})
 	}
 }
 
@@ -490,23 +780,29 @@ func (o *Ollama) newGenerateEmbeddingsFunc() GenerateEmbeddingsFunc {
 			f(&req)
 		}
 
-		body, err := o.stream(http.MethodPost, "/api/embeddings", req, 512000, nil)
-		if err != nil {
-			return nil, err
-		}
+		return invoke(o, http.MethodPost, "/api/embeddings", &req, func(o *Ollama) (*GenerateEmbeddingsResponse, error) {
+			body, err := o.stream(o.ctx, http.MethodPost, "/api/embeddings", req, 512000, nil)
+			if err != nil {
+				return nil, err
+			}
 
-		r, err := bodyTo[GenerateEmbeddingsResponse](body[0])
-		if err != nil {
-			return nil, err
-		}
+			if len(body) == 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
 
-		return r, nil
+			r, err := bodyTo[GenerateEmbeddingsResponse](body[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return r, nil
+		})
 	}
 }
 
 func (o *Ollama) newVersionFunc() VersionFunc {
 	return func() (*VersionResponse, error) {
-		res, err := o.request(http.MethodGet, "/api/version", nil)
+		res, err := o.request(o.ctx, http.MethodGet, "/api/version", nil)
 		if err != nil {
 			return nil, err
 		}
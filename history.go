@@ -0,0 +1,313 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// HistoryPolicy controls which messages of a chat's history are sent along with a request,
+// so that long-running chats fit into the context window of the model.
+// The stored history is never modified; only the messages sent to the API are trimmed.
+// The messages of the current request and the summary of a SummaryPolicy are always sent.
+type HistoryPolicy struct {
+	// MaxMessages keeps only the last N messages of the history, not counting pinned messages. Zero means no limit.
+	MaxMessages int `json:"max_messages,omitempty"`
+
+	// MaxTokens is the estimated token budget of the request. If zero, the budget is derived from
+	// Options.NumCtx of the request, or from the context length of the model when UseModelContext is set.
+	MaxTokens int `json:"max_tokens,omitempty"`
+
+	// ReserveTokens is subtracted from a derived budget, to leave room for the reply.
+	ReserveTokens int `json:"reserve_tokens,omitempty"`
+
+	// UseModelContext derives the budget from the model information returned by Models.ShowInfo,
+	// when neither MaxTokens nor Options.NumCtx are set.
+	UseModelContext bool `json:"use_model_context,omitempty"`
+
+	// PinSystem always keeps the system messages of the history.
+	PinSystem bool `json:"pin_system,omitempty"`
+
+	// PinFirstUser always keeps the first user message of the history.
+	PinFirstUser bool `json:"pin_first_user,omitempty"`
+
+	// EstimateTokens estimates the number of tokens of a message. Defaults to EstimateTokens.
+	EstimateTokens func(m Message) int `json:"-"`
+}
+
+// EstimateTokens estimates the number of tokens of a message, assuming about four characters per token.
+//
+// Parameters:
+//   - m: The message to estimate.
+func EstimateTokens(m Message) int {
+	n := 4 // Role and template overhead.
+	if m.Content != nil {
+		n += (len(*m.Content) + 3) / 4
+	}
+	for _, c := range m.ToolCalls {
+		if data, err := json2.Marshal(c.Function); err == nil {
+			n += (len(data) + 3) / 4
+		}
+	}
+	return n
+}
+
+// apply returns the messages of the history that should be sent along with the turn, in chronological order.
+func (p *HistoryPolicy) apply(history, turn []Message, budget int) []Message {
+	estimate := p.EstimateTokens
+	if estimate == nil {
+		estimate = EstimateTokens
+	}
+
+	used := 0
+	for _, m := range turn {
+		used += estimate(m)
+	}
+
+	keep := make([]bool, len(history))
+	firstUser := true
+	for i, m := range history {
+		role := ""
+		if m.Role != nil {
+			role = *m.Role
+		}
+
+		if (p.PinSystem && role == "system") || (p.PinFirstUser && firstUser && role == "user") {
+			keep[i] = true
+			used += estimate(m)
+		}
+
+		if role == "user" {
+			firstUser = false
+		}
+	}
+
+	// Keep the most recent messages that fit
+	kept := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		if keep[i] {
+			continue
+		}
+
+		if p.MaxMessages > 0 && kept >= p.MaxMessages {
+			break
+		}
+
+		tokens := estimate(history[i])
+		if budget > 0 && used+tokens > budget {
+			break
+		}
+
+		keep[i] = true
+		kept++
+		used += tokens
+	}
+
+	res := make([]Message, 0, len(history))
+	for i, m := range history {
+		if keep[i] {
+			res = append(res, m)
+		}
+	}
+
+	return res
+}
+
+// historyBudget returns the token budget of the request according to the policy, or zero if there is no budget.
+func (o *Ollama) historyBudget(p *HistoryPolicy, req *ChatRequestBuilder) int {
+	if p.MaxTokens > 0 {
+		return p.MaxTokens
+	}
+
+	numCtx := 0
+	if req.Options != nil && req.Options.NumCtx != nil {
+		numCtx = *req.Options.NumCtx
+	} else if p.UseModelContext && req.Model != nil {
+		numCtx = o.modelContextLength(*req.Model)
+	}
+
+	if numCtx == 0 {
+		return 0
+	}
+
+	budget := numCtx - p.ReserveTokens
+	if budget < 1 {
+		budget = 1
+	}
+	return budget
+}
+
+// modelContextLength returns the context length of the model reported by Models.ShowInfo, or zero if unknown.
+// Successful lookups are cached for the lifetime of the client.
+func (o *Ollama) modelContextLength(model string) int {
+	if v, ok := o.contextLengths.Load(model); ok {
+		return v.(int)
+	}
+
+	info, err := o.Models.ShowInfo(o.Models.ShowInfo.WithModel(model))
+	if err != nil {
+		return 0
+	}
+
+	for k, v := range info.ModelInfo {
+		if !strings.HasSuffix(k, ".context_length") {
+			continue
+		}
+
+		if n, ok := v.(float64); ok && n > 0 {
+			o.contextLengths.Store(model, int(n))
+			return int(n)
+		}
+	}
+
+	return 0
+}
+
+// DefaultSummaryPrompt is the instruction used to summarize the older messages of a chat.
+const DefaultSummaryPrompt = "Summarize the following conversation between a user and an assistant. " +
+	"Keep every fact, decision, name and open question needed to continue the conversation. " +
+	"Respond only with the summary."
+
+// SummaryPolicy condenses the older messages of a chat into a rolling summary once the history grows too long.
+// The summary is generated with a secondary Generate request, stored on the chat and sent in place of the
+// summarized messages, which are moved to Chat.Archived. System messages are never summarized.
+type SummaryPolicy struct {
+	// Threshold is the number of messages of the history above which the older messages are summarized.
+	Threshold int `json:"threshold"`
+
+	// KeepRecent is the number of most recent messages that are kept verbatim. Defaults to half the threshold.
+	KeepRecent int `json:"keep_recent,omitempty"`
+
+	// Model is the model that generates the summary. Defaults to the model of the chat request.
+	Model string `json:"model,omitempty"`
+
+	// Prompt is the instruction that precedes the conversation to summarize. Defaults to DefaultSummaryPrompt.
+	Prompt string `json:"prompt,omitempty"`
+
+	// Role is the role of the summary message sent with requests, either "system" (default) or "assistant".
+	Role string `json:"role,omitempty"`
+}
+
+// summarize condenses the older messages of the chat if its history exceeds the threshold of the policy.
+// It reports whether the chat was changed.
+func (o *Ollama) summarize(p *SummaryPolicy, chat *Chat, req *ChatRequestBuilder) (bool, error) {
+	if p.Threshold <= 0 || len(chat.Messages) <= p.Threshold {
+		return false, nil
+	}
+
+	keep := p.KeepRecent
+	if keep <= 0 || keep >= p.Threshold {
+		keep = p.Threshold / 2
+	}
+
+	model := p.Model
+	if len(model) == 0 && req.Model != nil {
+		model = *req.Model
+	}
+	if len(model) == 0 {
+		return false, errors.New("summary policy: no model to generate the summary")
+	}
+
+	older := chat.Messages[:len(chat.Messages)-keep]
+	recent := chat.Messages[len(chat.Messages)-keep:]
+
+	// The new index of each kept message, to move the alternatives along with it
+	kept := make(map[int]int, len(older)+len(recent))
+
+	system := make([]Message, 0)
+	summarized := make([]Message, 0, len(older))
+	for i, m := range older {
+		if m.Role != nil && *m.Role == "system" {
+			kept[i] = len(system)
+			system = append(system, m)
+		} else {
+			summarized = append(summarized, m)
+		}
+	}
+
+	for i := range recent {
+		kept[len(older)+i] = len(system) + i
+	}
+
+	if len(summarized) == 0 {
+		return false, nil
+	}
+
+	prompt := p.Prompt
+	if len(prompt) == 0 {
+		prompt = DefaultSummaryPrompt
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\n")
+	if len(chat.Summary) != 0 {
+		b.WriteString("Summary of the earlier conversation:\n")
+		b.WriteString(chat.Summary)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("Conversation:\n")
+	for _, m := range summarized {
+		role, content := "user", ""
+		if m.Role != nil {
+			role = *m.Role
+		}
+		if m.Content != nil {
+			content = *m.Content
+		}
+		b.WriteString(role + ": " + content + "\n")
+	}
+
+	res, err := o.Generate(
+		o.Generate.WithModel(model),
+		o.Generate.WithPrompt(b.String()),
+	)
+	if err != nil {
+		return false, fmt.Errorf("summary policy: %w", err)
+	}
+
+	chat.Summary = strings.TrimSpace(res.Response)
+	chat.Archived = append(chat.Archived, summarized...)
+	chat.Messages = append(system, recent...)
+
+	// The alternatives of the summarized messages are dropped
+	if len(chat.Alternatives) != 0 {
+		alternatives := make(map[int][]Message, len(chat.Alternatives))
+		for i, m := range chat.Alternatives {
+			if j, ok := kept[i]; ok {
+				alternatives[j] = m
+			}
+		}
+		chat.Alternatives = alternatives
+	}
+
+	return true, nil
+}
+
+// summaryMessage returns the message that is sent in place of the summarized messages.
+// The policy may be nil, in which case the summary is sent as a system message.
+func summaryMessage(p *SummaryPolicy, summary string) Message {
+	role := "system"
+	if p != nil && len(p.Role) != 0 {
+		role = p.Role
+	}
+
+	return Message{
+		Role:    &role,
+		Content: pointer("Summary of the earlier conversation:\n" + summary),
+	}
+}
+
+// withSummary inserts the summary message after the leading system messages of the history.
+func withSummary(history []Message, summary Message) []Message {
+	i := 0
+	for i < len(history) && history[i].Role != nil && *history[i].Role == "system" {
+		i++
+	}
+
+	res := make([]Message, 0, len(history)+1)
+	res = append(res, history[:i]...)
+	res = append(res, summary)
+	return append(res, history[i:]...)
+}
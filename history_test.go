@@ -0,0 +1,223 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHistoryPolicy(t *testing.T) {
+	history := []Message{
+		{Role: pointer("system"), Content: pointer("s")},
+		{Role: pointer("user"), Content: pointer("u1")},
+		{Role: pointer("assistant"), Content: pointer("a1")},
+		{Role: pointer("user"), Content: pointer("u2")},
+		{Role: pointer("assistant"), Content: pointer("a2")},
+		{Role: pointer("user"), Content: pointer("u3")},
+		{Role: pointer("assistant"), Content: pointer("a3")},
+	}
+	turn := []Message{{Role: pointer("user"), Content: pointer("u4")}}
+
+	// Every message is estimated as 1 token, including the message of the turn.
+	one := func(Message) int { return 1 }
+
+	tests := []struct {
+		name     string
+		policy   HistoryPolicy
+		budget   int
+		expected string
+	}{
+		{"no limits", HistoryPolicy{}, 0, "[s u1 a1 u2 a2 u3 a3]"},
+		{"last messages", HistoryPolicy{MaxMessages: 2}, 0, "[u3 a3]"},
+		{"pinned", HistoryPolicy{MaxMessages: 2, PinSystem: true, PinFirstUser: true}, 0, "[s u1 u3 a3]"},
+		{"budget", HistoryPolicy{EstimateTokens: one}, 4, "[a2 u3 a3]"},
+		{"pinned budget", HistoryPolicy{PinSystem: true, EstimateTokens: one}, 4, "[s u3 a3]"},
+		{"exhausted budget", HistoryPolicy{PinSystem: true, EstimateTokens: one}, 1, "[s]"},
+	}
+
+	for _, test := range tests {
+		res := test.policy.apply(history, turn, test.budget)
+
+		contents := make([]string, 0, len(res))
+		for _, m := range res {
+			contents = append(contents, *m.Content)
+		}
+
+		if got := fmt.Sprint(contents); got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestHistoryPolicyNumCtx(t *testing.T) {
+	llm, requests := chatServer(t)
+	chatId := "trim"
+
+	policy := HistoryPolicy{ReserveTokens: 2, EstimateTokens: func(Message) int { return 1 }}
+	llm.PreloadChat(Chat{ID: chatId, HistoryPolicy: &policy})
+
+	for i := 0; i < 5; i++ {
+		_, err := llm.Chat(
+			&chatId,
+			llm.Chat.WithModel("llama3"),
+			llm.Chat.WithOptions(Options{NumCtx: pointer(6)}),
+			llm.Chat.WithMessage(Message{Content: pointer(fmt.Sprint(i))}),
+		)
+		if err != nil {
+			t.Fatalf("Chat returned an error: %s", err)
+		}
+	}
+
+	// A budget of 4 messages leaves room for the 3 latest messages of the history and the new one.
+	last := (*requests)[len(*requests)-1]
+	if len(last) != 4 || *last[3].Content != "4" {
+		t.Errorf("Expected 4 messages ending with the new one, got %d", len(last))
+	}
+
+	if chat := mustGetChat(t, llm, chatId); len(chat.Messages) != 10 {
+		t.Errorf("Expected the stored history to be untouched, got %d messages", len(chat.Messages))
+	}
+}
+
+func TestSummaryPolicy(t *testing.T) {
+	var prompts []string
+	var last []Message
+
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/generate":
+			var req GenerateRequestBuilder
+			json2.NewDecoder(r.Body).Decode(&req)
+			prompts = append(prompts, *req.Prompt)
+			json2.NewEncoder(w).Encode(GenerateResponse{Response: fmt.Sprintf("summary %d", len(prompts)), Done: true})
+		case "/api/chat":
+			var req ChatRequestBuilder
+			json2.NewDecoder(r.Body).Decode(&req)
+			last = req.Messages
+			json2.NewEncoder(w).Encode(ChatResponse{Message: Message{Role: pointer("assistant"), Content: pointer("ok")}, Done: true})
+		}
+	})
+
+	chatId := "summary"
+	llm.PreloadChat(Chat{
+		ID:            chatId,
+		Messages:      []Message{{Role: pointer("system"), Content: pointer("be nice")}},
+		SummaryPolicy: &SummaryPolicy{Threshold: 6, KeepRecent: 2, Model: "small"},
+	})
+
+	for i := 0; i < 4; i++ {
+		_, err := llm.Chat(&chatId, llm.Chat.WithModel("llama3"), llm.Chat.WithMessage(Message{Content: pointer(fmt.Sprint("question ", i))}))
+		if err != nil {
+			t.Fatalf("Chat returned an error: %s", err)
+		}
+	}
+
+	// The history reached 7 messages before the 4th request, so it was summarized once.
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "user: question 0") || strings.Contains(prompts[0], "be nice") {
+		t.Fatalf("Unexpected summary prompts: %q", prompts)
+	}
+
+	chat := mustGetChat(t, llm, chatId)
+	if chat.Summary != "summary 1" || len(chat.Archived) != 4 || len(chat.Messages) != 5 {
+		t.Errorf("Unexpected chat after summary: %q, %d archived, %d messages", chat.Summary, len(chat.Archived), len(chat.Messages))
+	}
+
+	if len(last) != 5 || *last[0].Content != "be nice" || *last[1].Content != "Summary of the earlier conversation:\nsummary 1" {
+		t.Errorf("Unexpected messages sent after summary: %d", len(last))
+	}
+}
+
+func TestSummaryPolicyAlternatives(t *testing.T) {
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/generate":
+			json2.NewEncoder(w).Encode(GenerateResponse{Response: "summary", Done: true})
+		case "/api/chat":
+			json2.NewEncoder(w).Encode(ChatResponse{Message: Message{Role: pointer("assistant"), Content: pointer("ok")}, Done: true})
+		}
+	})
+
+	message := func(role, content string) Message {
+		return Message{Role: pointer(role), Content: pointer(content)}
+	}
+
+	chatId := "summary"
+	llm.PreloadChat(Chat{
+		ID: chatId,
+		Messages: []Message{
+			message("system", "be nice"),
+			message("user", "question 0"),
+			message("assistant", "answer 0"),
+			message("user", "question 1"),
+			message("assistant", "answer 1"),
+			message("user", "question 2"),
+			message("assistant", "answer 2"),
+		},
+		Alternatives: map[int][]Message{
+			0: {message("system", "be brief")},
+			2: {message("assistant", "other answer 0")},
+			6: {message("assistant", "other answer 2")},
+		},
+		SummaryPolicy: &SummaryPolicy{Threshold: 6, KeepRecent: 2, Model: "small"},
+	})
+
+	_, err := llm.Chat(&chatId, llm.Chat.WithModel("llama3"), llm.Chat.WithMessage(message("user", "question 3")))
+	if err != nil {
+		t.Fatalf("Chat returned an error: %s", err)
+	}
+
+	// The system message and the 2 recent messages are kept, followed by the new turn
+	chat := mustGetChat(t, llm, chatId)
+	if len(chat.Messages) != 5 || *chat.Messages[2].Content != "answer 2" {
+		t.Fatalf("Unexpected chat after summary: %d messages", len(chat.Messages))
+	}
+
+	if len(chat.Alternatives) != 2 || *chat.Alternatives[0][0].Content != "be brief" || *chat.Alternatives[2][0].Content != "other answer 2" {
+		t.Fatalf("Expected the alternatives to follow their messages, got %v", chat.Alternatives)
+	}
+
+	if err := chat.SelectAlternative(2, 0); err != nil {
+		t.Fatalf("SelectAlternative returned an error: %s", err)
+	}
+	if *chat.Messages[2].Content != "other answer 2" {
+		t.Errorf("Expected the reply to be swapped with its alternative, got %q", *chat.Messages[2].Content)
+	}
+}
+
+func TestSummaryWithHistoryPolicy(t *testing.T) {
+	var last []Message
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequestBuilder
+		json2.NewDecoder(r.Body).Decode(&req)
+		last = req.Messages
+		json2.NewEncoder(w).Encode(ChatResponse{Message: Message{Role: pointer("assistant"), Content: pointer("ok")}, Done: true})
+	})
+
+	message := func(role, content string) Message {
+		return Message{Role: pointer(role), Content: pointer(content)}
+	}
+
+	for _, role := range []string{"system", "assistant"} {
+		chatId := "summary-" + role
+		llm.PreloadChat(Chat{
+			ID:            chatId,
+			Messages:      []Message{message("user", "question 1"), message("assistant", "answer 1"), message("user", "question 2"), message("assistant", "answer 2")},
+			HistoryPolicy: &HistoryPolicy{MaxMessages: 2},
+			SummaryPolicy: &SummaryPolicy{Threshold: 10, Role: role},
+			Summary:       "earlier",
+		})
+
+		_, err := llm.Chat(&chatId, llm.Chat.WithModel("llama3"), llm.Chat.WithMessage(message("user", "question 3")))
+		if err != nil {
+			t.Fatalf("Chat returned an error: %s", err)
+		}
+
+		// The summary is kept along with the last 2 messages and the turn
+		if len(last) != 4 || *last[0].Role != role || *last[0].Content != "Summary of the earlier conversation:\nearlier" ||
+			*last[1].Content != "question 2" || *last[3].Content != "question 3" {
+			t.Errorf("Unexpected messages sent with the summary role %s: %d", role, len(last))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Call is a call to the Ollama API passing through the middlewares of a client.
+type Call struct {
+	// Context is the context of the call. A middleware may replace it, for example to add a deadline.
+	Context context.Context
+
+	Method string // The HTTP method, such as "POST".
+	Path   string // The path of the endpoint, such as "/api/chat".
+
+	// Request is the request builder, such as *ChatRequestBuilder, before it is marshaled.
+	// A middleware may change it in place. It is nil for the calls without a builder,
+	// such as Models.List, and a *BlobRequest, *CopyModelRequest or *DeleteModelRequest for the calls
+	// whose functions take plain arguments.
+	Request interface{}
+}
+
+// BlobRequest is the Request of the Blobs.Check and Blobs.Create calls.
+type BlobRequest struct {
+	Digest string
+	Data   []byte // The content of the blob, only set for Blobs.Create.
+}
+
+// CopyModelRequest is the Request of the Models.Copy calls.
+type CopyModelRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// DeleteModelRequest is the Request of the Models.Delete calls.
+type DeleteModelRequest struct {
+	Model string `json:"model"`
+}
+
+// Handler performs a call and returns its final response, the pointer returned by the API function,
+// such as *ChatResponse once the streamed responses are merged, or *Stream[ChatResponse] for ChatStream.
+// The response is nil for the calls that only return an error, such as Blobs.Check.
+type Handler func(call *Call) (interface{}, error)
+
+// Middleware wraps the handler of the calls of a client. It can inspect or change the request before calling next,
+// inspect or change the response after, or return a response without calling next, for example to serve it from
+// a cache. A response returned without calling next must have the type that the API function returns.
+//
+// Example:
+//
+//	llm.Use(func(next ollama.Handler) ollama.Handler {
+//		return func(call *ollama.Call) (interface{}, error) {
+//			start := time.Now()
+//			res, err := next(call)
+//			log.Printf("%s %s took %s", call.Method, call.Path, time.Since(start))
+//			return res, err
+//		}
+//	})
+type Middleware func(next Handler) Handler
+
+// HTTPHandler sends an HTTP request and returns its response.
+type HTTPHandler func(req *http.Request) (*http.Response, error)
+
+// HTTPMiddleware wraps the HTTP requests of a client. It is called for each attempt of a request, so it can sign
+// the request or refresh a token. Responses with an error status code are converted to an *APIError after it.
+type HTTPMiddleware func(next HTTPHandler) HTTPHandler
+
+// middlewares are the middlewares registered on a client.
+type middlewares struct {
+	calls []Middleware
+	http  []HTTPMiddleware
+}
+
+// Use adds middlewares that wrap the calls of the client. The first middleware added is the outermost one.
+// It should be called before the client is used.
+//
+// Parameters:
+//   - m: The middlewares to add.
+func (o *Ollama) Use(m ...Middleware) {
+	o.middlewares.calls = append(o.middlewares.calls, m...)
+}
+
+// UseHTTP adds middlewares that wrap the HTTP requests of the client. The first middleware added is the outermost one.
+// It should be called before the client is used.
+//
+// Parameters:
+//   - m: The middlewares to add.
+func (o *Ollama) UseHTTP(m ...HTTPMiddleware) {
+	o.middlewares.http = append(o.middlewares.http, m...)
+}
+
+// invoke passes a call through the middlewares of the client and performs it with fn,
+// using a copy of the client bound to the context of the call.
+func invoke[T any](o *Ollama, method, path string, req interface{}, fn func(o *Ollama) (*T, error)) (*T, error) {
+	if len(o.middlewares.calls) == 0 {
+		return fn(o)
+	}
+
+	handler := func(call *Call) (interface{}, error) {
+		res, err := fn(o.WithContext(call.Context))
+		if res == nil {
+			// A nil *T would not be a nil interface
+			return nil, err
+		}
+		return res, err
+	}
+
+	next := Handler(handler)
+	for i := len(o.middlewares.calls) - 1; i >= 0; i-- {
+		next = o.middlewares.calls[i](next)
+	}
+
+	res, err := next(&Call{Context: o.ctx, Method: method, Path: path, Request: req})
+	if res == nil {
+		return nil, err
+	}
+
+	v, ok := res.(*T)
+	if !ok {
+		return nil, fmt.Errorf("middleware of %s %s returned a %T response, expected %T", method, path, res, v)
+	}
+	return v, err
+}
+
+// do sends an HTTP request through the HTTP middlewares of the client.
+func (o *Ollama) do(req *http.Request) (*http.Response, error) {
+	next := HTTPHandler(o.Http.Do)
+	for i := len(o.middlewares.http) - 1; i >= 0; i-- {
+		next = o.middlewares.http[i](next)
+	}
+
+	return next(req)
+}
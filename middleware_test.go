@@ -0,0 +1,317 @@
+package ollama
+
+import (
+	"context"
+	json2 "encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type middlewareKey struct{}
+
+func TestMiddlewareChat(t *testing.T) {
+	var model interface{}
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json2.NewDecoder(r.Body).Decode(&body)
+		model = body["model"]
+
+		w.Write([]byte(`{"message":{"role":"assistant","content":"Hel"},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"lo"},"done":true}` + "\n"))
+	})
+
+	var trace []string
+	var seen *ChatRequestBuilder
+	llm.Use(
+		func(next Handler) Handler {
+			return func(call *Call) (interface{}, error) {
+				trace = append(trace, "outer "+call.Method+" "+call.Path)
+				res, err := next(call)
+				trace = append(trace, "outer done")
+				return res, err
+			}
+		},
+		func(next Handler) Handler {
+			return func(call *Call) (interface{}, error) {
+				seen = call.Request.(*ChatRequestBuilder)
+				seen.Model = pointer("override")
+				trace = append(trace, "inner")
+
+				res, err := next(call)
+				if r, ok := res.(*ChatResponse); ok {
+					trace = append(trace, "inner done "+*r.Message.Content)
+				}
+				return res, err
+			}
+		},
+	)
+
+	chatId := "middleware"
+	res, err := llm.Chat(&chatId,
+		llm.Chat.WithModel("llama3"),
+		llm.Chat.WithMessage(Message{Role: pointer("user"), Content: pointer("hi")}),
+		llm.Chat.WithStream(true, 512000, nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *res.Message.Content != "Hello" {
+		t.Errorf("expected the reply \"Hello\", got %q", *res.Message.Content)
+	}
+	if model != "override" {
+		t.Errorf("expected the request changed by the middleware, got the model %v", model)
+	}
+	if len(seen.Messages) != 1 {
+		t.Errorf("expected the middleware to see the messages of the request, got %d", len(seen.Messages))
+	}
+
+	expected := []string{"outer POST /api/chat", "inner", "inner done Hello", "outer done"}
+	if strings.Join(trace, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected the trace %v, got %v", expected, trace)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	var requests int32
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"live"},"done":true}`))
+	})
+
+	// A cache of the replies, keyed by the last message
+	cache := map[string]*ChatResponse{
+		"cached": {Message: Message{Role: pointer("assistant"), Content: pointer("from cache")}, Done: true},
+	}
+	llm.Use(func(next Handler) Handler {
+		return func(call *Call) (interface{}, error) {
+			req, ok := call.Request.(*ChatRequestBuilder)
+			if !ok {
+				return next(call)
+			}
+
+			last := req.Messages[len(req.Messages)-1]
+			if res, ok := cache[*last.Content]; ok {
+				return res, nil
+			}
+			return next(call)
+		}
+	})
+
+	chatId := "cache"
+	res, err := llm.Chat(&chatId, llm.Chat.WithMessage(Message{Role: pointer("user"), Content: pointer("cached")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *res.Message.Content != "from cache" {
+		t.Errorf("expected the cached reply, got %q", *res.Message.Content)
+	}
+	if atomic.LoadInt32(&requests) != 0 {
+		t.Errorf("expected no request to be sent, got %d", requests)
+	}
+
+	if _, err := llm.Chat(&chatId, llm.Chat.WithMessage(Message{Role: pointer("user"), Content: pointer("new")})); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("expected 1 request to be sent, got %d", requests)
+	}
+
+	// The cached reply is stored in the chat like any other
+	chat := mustGetChat(t, llm, chatId)
+	if len(chat.Messages) != 4 || *chat.Messages[1].Content != "from cache" {
+		t.Errorf("expected the cached reply in the chat, got %v", chat.Messages)
+	}
+}
+
+func TestMiddlewareWrongResponseType(t *testing.T) {
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+
+	llm.Use(func(next Handler) Handler {
+		return func(call *Call) (interface{}, error) {
+			return &ChatResponse{}, nil
+		}
+	})
+
+	if _, err := llm.Generate(llm.Generate.WithPrompt("hi")); err == nil {
+		t.Error("expected an error for a response of the wrong type")
+	}
+}
+
+func TestMiddlewareRequests(t *testing.T) {
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[]}`))
+		case "/api/embeddings":
+			w.Write([]byte(`{"embedding":[0.1]}`))
+		}
+	})
+
+	var calls []string
+	llm.Use(func(next Handler) Handler {
+		return func(call *Call) (interface{}, error) {
+			switch req := call.Request.(type) {
+			case *BlobRequest:
+				calls = append(calls, call.Path+" "+req.Digest+" "+string(req.Data))
+			case *CopyModelRequest:
+				calls = append(calls, call.Path+" "+req.Source+" "+req.Destination)
+			case *DeleteModelRequest:
+				calls = append(calls, call.Path+" "+req.Model)
+			case *GenerateEmbeddingsRequestBuilder:
+				calls = append(calls, call.Path+" "+*req.Prompt)
+			case nil:
+				calls = append(calls, call.Path)
+			}
+			return next(call)
+		}
+	})
+
+	if err := llm.Blobs.Create("sha256:abc", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := llm.Blobs.Check("sha256:abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := llm.Models.Copy("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := llm.Models.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := llm.Models.List(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := llm.GenerateEmbeddings(llm.GenerateEmbeddings.WithPrompt("text")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"/api/blobs/sha256:abc sha256:abc data",
+		"/api/blobs/sha256:abc sha256:abc ",
+		"/api/copy a b",
+		"/api/delete b",
+		"/api/tags",
+		"/api/embeddings text",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected the calls %q, got %q", expected, calls)
+	}
+}
+
+func TestMiddlewareContext(t *testing.T) {
+	release := make(chan struct{})
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+
+	var value interface{}
+	llm.UseHTTP(func(next HTTPHandler) HTTPHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			value = req.Context().Value(middlewareKey{})
+			return next(req)
+		}
+	})
+
+	llm.Use(func(next Handler) Handler {
+		return func(call *Call) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(call.Context, 50*time.Millisecond)
+			defer cancel()
+
+			call.Context = context.WithValue(ctx, middlewareKey{}, "traced")
+			return next(call)
+		}
+	})
+
+	_, err := llm.Generate(llm.Generate.WithPrompt("hi"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline of the middleware, got %v", err)
+	}
+	if value != "traced" {
+		t.Errorf("expected the context of the middleware in the HTTP request, got %v", value)
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	var attempts int32
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&attempts, 1)
+		if r.Header.Get("Authorization") != "Bearer token-"+strconv.Itoa(int(n)) {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"models":[]}`))
+	})
+	llm.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+	// A new token is signed for each attempt
+	var tokens int32
+	var statuses []int
+	llm.UseHTTP(func(next HTTPHandler) HTTPHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "Bearer token-"+strconv.Itoa(int(atomic.AddInt32(&tokens, 1))))
+
+			res, err := next(req)
+			if res != nil {
+				statuses = append(statuses, res.StatusCode)
+			}
+			return res, err
+		}
+	})
+
+	if _, err := llm.Models.List(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 2 || statuses[0] != http.StatusServiceUnavailable || statuses[1] != http.StatusOK {
+		t.Errorf("expected the middleware to see each attempt, got %v", statuses)
+	}
+}
+
+func TestHTTPMiddlewareShortCircuit(t *testing.T) {
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+
+	llm.UseHTTP(func(next HTTPHandler) HTTPHandler {
+		return func(req *http.Request) (*http.Response, error) {
+			status, body := http.StatusOK, `{"response":"mocked","done":true}`
+			if req.URL.Path == "/api/tags" {
+				status, body = http.StatusNotFound, `{"error":"not found"}`
+			}
+
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		}
+	})
+
+	res, err := llm.Generate(llm.Generate.WithPrompt("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "mocked" {
+		t.Errorf("expected the mocked response, got %q", res.Response)
+	}
+
+	// Error responses are converted after the middlewares
+	_, err = llm.Models.List()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
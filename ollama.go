@@ -2,23 +2,35 @@ package ollama
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 )
 
 // Ollama represents a client for interacting with the Ollama API.
 type Ollama struct {
 	url     url.URL
+	ctx     context.Context
 	Http    *http.Client
-	chats   map[string]*Chat
+	chats   ChatStore
+	turns   *keyedMutex
 	headers map[string][]string
 
-	Chat     ChatFunc
-	Generate GenerateFunc
+	contextLengths *sync.Map
+	retryPolicy    *RetryPolicy
+	middlewares    *middlewares
+	defaults       requestDefaults
+	err            error // The error of the options of New, returned by every request.
+
+	Chat           ChatFunc
+	ChatStream     ChatStreamFunc
+	Generate       GenerateFunc
+	GenerateStream GenerateStreamFunc
 
 	Blobs struct {
 		Check  BlobCheckFunc
@@ -26,130 +38,335 @@ type Ollama struct {
 	}
 
 	Models struct {
-		Create   CreateModelFunc
-		List     ListLocalModelsFunc
-		ShowInfo ShowModelInfoFunc
-		Copy     CopyModelFunc
-		Delete   DeleteModelFunc
-		Pull     PullModelFunc
-		Push     PushModelFunc
+		Create       CreateModelFunc
+		CreateStream CreateModelStreamFunc
+		List         ListLocalModelsFunc
+		ShowInfo     ShowModelInfoFunc
+		Copy         CopyModelFunc
+		Delete       DeleteModelFunc
+		Pull         PullModelFunc
+		PullStream   PullModelStreamFunc
+		Push         PushModelFunc
+		PushStream   PushModelStreamFunc
 	}
 
 	GenerateEmbeddings GenerateEmbeddingsFunc
 }
 
 // New creates a new Ollama client that points to the specified URL.
-// It initializes the client with default settings and available API functions.
+// A unix:// URL, such as unix:///var/run/ollama.sock, connects to the server through a unix domain socket.
+// It requires the transport to be an *http.Transport, otherwise every request fails with an error.
+// It initializes the client with default settings and available API functions,
+// which can be changed with options such as WithReadTimeout or WithDefaultModel.
 //
 // Example:
 //
-//	llm := New("http://api.ollama.com")
-func New(v url.URL) *Ollama {
+//	llm := New(*uri, WithDefaultModel("llama3"), WithConnectTimeout(5*time.Second))
+//
+// Parameters:
+//   - v: The URL of the Ollama server.
+//   - opts: The options of the client.
+func New(v url.URL, opts ...ClientOption) *Ollama {
+	c := clientConfig{headers: make(map[string][]string)}
+	for _, f := range opts {
+		f(&c)
+	}
+
+	if v.Scheme == "unix" {
+		// The path of the URL is the socket, while the requests are sent to a placeholder host
+		c.socket = v.Path
+		v = url.URL{Scheme: "http", Host: "localhost"}
+	}
+
+	if len(c.basePath) != 0 {
+		v = *v.JoinPath(c.basePath)
+	}
+
+	httpClient, err := c.httpClient()
+
 	o := &Ollama{
 		url:     v,
-		Http:    &http.Client{},
-		chats:   make(map[string]*Chat),
-		headers: make(map[string][]string),
+		ctx:     context.Background(),
+		Http:    httpClient,
+		chats:   NewMemoryChatStore(),
+		turns:   &keyedMutex{},
+		headers: c.headers,
+
+		contextLengths: &sync.Map{},
+		retryPolicy:    &RetryPolicy{},
+		middlewares:    &middlewares{},
+		defaults:       c.defaults,
+		err:            err,
 	}
 
+	o.init()
+	return o
+}
+
+// WithContext returns a shallow copy of the client whose requests are bound to ctx.
+// Cancelling ctx aborts any in-flight request, including streaming ones, and the
+// function returns ctx.Err() along with the partial response received so far.
+// The copy shares the chats, headers, middlewares and HTTP client with the original client.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	res, err := llm.WithContext(ctx).Generate(...)
+func (o *Ollama) WithContext(ctx context.Context) *Ollama {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	c := *o
+	c.ctx = ctx
+	c.init()
+	return &c
+}
+
+// init binds the API functions to the client.
+func (o *Ollama) init() {
 	o.Chat = o.newChatFunc()
+	o.ChatStream = o.newChatStreamFunc()
 	o.Generate = o.newGenerateFunc()
+	o.GenerateStream = o.newGenerateStreamFunc()
 
 	o.Blobs.Check = o.newBlobCheckFunc()
 	o.Blobs.Create = o.newBlobCreateFunc()
 
 	o.Models.Create = o.newCreateModelFunc()
+	o.Models.CreateStream = o.newCreateModelStreamFunc()
 	o.Models.List = o.newListLocalModelsFunc()
 	o.Models.ShowInfo = o.newShowModelInfoFunc()
 	o.Models.Copy = o.newCopyModelFunc()
 	o.Models.Delete = o.newDeleteModelFunc()
 	o.Models.Pull = o.newPullModelFunc()
+	o.Models.PullStream = o.newPullModelStreamFunc()
 	o.Models.Push = o.newPushModelFunc()
+	o.Models.PushStream = o.newPushModelStreamFunc()
 
 	o.GenerateEmbeddings = o.newGenerateEmbeddingsFunc()
+}
 
-	return o
+// SetChatStore sets the store of the chats. By default, chats are stored in memory.
+// It should be called before the client is used.
+//
+// Parameters:
+//   - store: The chat store.
+func (o *Ollama) SetChatStore(store ChatStore) {
+	o.chats = store
 }
 
-// PreloadChat preloads a chat into the client's chat map.
+// ChatStore returns the store of the chats.
+func (o *Ollama) ChatStore() ChatStore {
+	return o.chats
+}
+
+// PreloadChat preloads a chat into the client's chat store.
 //
 // Parameters:
 //   - chat: The chat to preload.
-func (o *Ollama) PreloadChat(chat Chat) {
-	o.chats[chat.ID] = &chat
+func (o *Ollama) PreloadChat(chat Chat) error {
+	return o.chats.Put(&chat)
 }
 
-// GetChat retrieves a chat by its ID.
+// GetChat retrieves a copy of a chat by its ID.
+// Changes to the chat must be saved with PreloadChat.
 //
 // Parameters:
 //   - id: The ID of the chat.
 //
 // Returns:
 //   - A pointer to the Chat if found, or nil if not found.
-func (o *Ollama) GetChat(id string) *Chat {
-	return o.chats[id]
+func (o *Ollama) GetChat(id string) (*Chat, error) {
+	return o.chats.Get(id)
 }
 
 // DeleteChat removes a chat by its ID.
 //
 // Parameters:
 //   - id: The ID of the chat to remove.
-func (o *Ollama) DeleteChat(id string) {
-	delete(o.chats, id)
+func (o *Ollama) DeleteChat(id string) error {
+	return o.chats.Delete(id)
 }
 
-// DeleteAllChats removes all chats from the client's chat map.
-func (o *Ollama) DeleteAllChats() {
-	o.chats = make(map[string]*Chat, 0)
-}
+// DeleteAllChats removes all chats from the client's chat store.
+func (o *Ollama) DeleteAllChats() error {
+	ids, err := o.chats.List()
+	if err != nil {
+		return err
+	}
 
-// SetHeaders sets the headers for all the requests.
-func (o *Ollama) SetHeaders(key string, value []string) {
-	o.headers[key] = value
+	for _, id := range ids {
+		if err := o.chats.Delete(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (o *Ollama) stream(method, path string, data interface{}, maxBufferSize int, streamFunc func(b []byte)) ([][]byte, error) {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
+// Regenerate drops the last reply of a chat and asks the model again, using the messages up to the last user message.
+// The new reply replaces the messages that followed the last user message once the request succeeds.
+//
+// Parameters:
+//   - chatId: The ID of the chat.
+//   - keepAlternative: Whether to store the dropped reply in Chat.Alternatives, so it can be selected again.
+//   - builder: The options of the request, as passed to Chat. Messages cannot be added with WithMessage.
+func (o *Ollama) Regenerate(chatId string, keepAlternative bool, builder ...func(reqBuilder *ChatRequestBuilder)) (*ChatResponse, error) {
+	req := ChatRequestBuilder{}
+	o.defaults.applyChat(&req)
+	for _, f := range builder {
+		f(&req)
+	}
+
+	if len(req.Messages) != 0 {
+		return nil, errors.New("regenerate: messages cannot be added to the request")
+	}
+
+	if req.Stream == nil {
+		req.Stream = pointer(false)
 	}
 
-	resp, err := o.request(method, path, bytes.NewBuffer(jsonData))
+	if req.StreamBufferSize == nil {
+		req.StreamBufferSize = pointer(512000)
+	}
+
+	unlock := o.turns.Lock(chatId)
+	defer unlock()
+
+	chat, err := o.chats.Get(chatId)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var res [][]byte
-	var buffer bytes.Buffer
+	if chat == nil {
+		return nil, fmt.Errorf("regenerate: chat %s not found", chatId)
+	}
 
-	for {
-		buf := make([]byte, maxBufferSize)
-		n, err := resp.Body.Read(buf)
-		if err != nil && err != io.EOF {
-			return nil, err
-		}
+	messages := chat.GetMessages()
 
-		if n == 0 {
+	last := len(messages) - 1
+	for last >= 0 && (messages[last].Role == nil || *messages[last].Role != "user") {
+		last--
+	}
+
+	if last < 0 {
+		return nil, fmt.Errorf("regenerate: chat %s has no user message", chatId)
+	}
+
+	// RewindTo drops the alternatives of the user message and of the reply, so they are restored after it
+	turn := []Message{messages[last]}
+	turnAlternatives := chat.Alternatives[last]
+	alternatives := chat.Alternatives[last+1]
+
+	var reply *Message
+	for i := len(messages) - 1; i > last; i-- {
+		if messages[i].Role != nil && *messages[i].Role == "assistant" {
+			reply = &messages[i]
 			break
 		}
+	}
 
-		bigChunk := splitJSONObjects(buf[:n])
-		for _, chunk := range bigChunk {
-			res = append(res, chunk)
-			buffer.Write(chunk)
+	if err := chat.RewindTo(last); err != nil {
+		return nil, err
+	}
+
+	if req.KeepThinking == nil {
+		req.KeepThinking = pointer(chat.KeepThinking)
+	}
+	req.Messages = append(o.chatHistory(chat, turn, &req), turn...)
+
+	final, err := o.sendChat(&req)
+	if err != nil {
+		return final, err
+	}
 
-			if streamFunc != nil {
-				streamFunc(chunk)
+	chat.AddMessage(turn[0])
+	chat.AddMessage(storedReply(&req, final.Message))
+
+	for _, m := range turnAlternatives {
+		chat.AddAlternative(last, m)
+	}
+
+	for _, m := range alternatives {
+		chat.AddAlternative(last+1, m)
+	}
+
+	if keepAlternative && reply != nil {
+		chat.AddAlternative(last+1, *reply)
+	}
+
+	if err := o.chats.Put(chat); err != nil {
+		return final, err
+	}
+
+	return final, nil
+}
+
+// SetHeaders sets the headers for all the requests.
+func (o *Ollama) SetHeaders(key string, value []string) {
+	o.headers[key] = value
+}
+
+// SetRetryPolicy sets the policy used to retry the requests that fail with a transient error.
+// Retries are disabled by default. It should be called before the client is used, as the policy
+// is shared with the copies returned by WithContext.
+//
+// Parameters:
+//   - p: The retry policy, such as DefaultRetryPolicy.
+func (o *Ollama) SetRetryPolicy(p RetryPolicy) {
+	*o.retryPolicy = p
+}
+
+// stream performs a request and reads the response body as a sequence of newline delimited JSON objects.
+// The buffer size controls how many bytes are read from the body at once.
+//
+// If the stream fails after it has started, for example because ctx is done or the API sent an
+// {"error": "..."} frame, the stream function is called with the error and the objects received
+// so far are returned along with it. If the stream function returns an error, the connection is
+// closed and the objects received so far are returned along with that error.
+func (o *Ollama) stream(ctx context.Context, method, path string, data interface{}, bufferSize int, streamFunc func(b []byte, err error) error) ([][]byte, error) {
+	s, err := openStream[json.RawMessage](o, ctx, method, path, data, bufferSize)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	var res [][]byte
+	for s.Next() {
+		chunk := []byte(*s.Current())
+		res = append(res, chunk)
+
+		if streamFunc != nil {
+			if err := streamFunc(chunk, nil); err != nil {
+				return res, err
 			}
 		}
 	}
 
+	if err := s.Err(); err != nil {
+		if streamFunc != nil {
+			streamFunc(nil, err)
+		}
+		return res, err
+	}
+
 	return res, nil
 }
 
-func (o *Ollama) request(method, path string, body io.Reader) (*http.Response, error) {
-	httpReq, err := http.NewRequest(method, o.url.JoinPath(path).String(), body)
+// request performs a single attempt of a request. The body is a byte slice, so the request can be sent again.
+func (o *Ollama) request(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, o.url.JoinPath(path).String(), reader)
 	if err != nil {
 		return nil, err
 	}
@@ -164,18 +381,20 @@ func (o *Ollama) request(method, path string, body io.Reader) (*http.Response, e
 		}
 	}
 
-	httpResp, err := o.Http.Do(httpReq)
+	httpResp, err := o.do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 
 	if httpResp.StatusCode >= 400 {
 		respBody, err := io.ReadAll(httpResp.Body)
+		httpResp.Body.Close() // Ensure the body is closed
 		if err != nil {
 			return nil, fmt.Errorf("status code: %d, failed to read response body: %w", httpResp.StatusCode, err)
 		}
-		httpResp.Body.Close() // Ensure the body is closed
-		return nil, errors.New(fmt.Sprintf("status code: %d, body: %s", httpResp.StatusCode, string(respBody)))
+		apiErr := newAPIError(method, path, httpResp.StatusCode, respBody)
+		apiErr.RetryAfter = parseRetryAfter(httpResp.Header.Get("Retry-After"))
+		return nil, apiErr
 	}
 
 	return httpResp, nil
@@ -1,11 +1,16 @@
 package ollama
 
 import (
+	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 var LLM *Ollama
@@ -20,6 +25,15 @@ func init() {
 	LLM = New(*uri)
 }
 
+// newTestClient starts a fake Ollama server with the given handler and returns a client pointing to it.
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...ClientOption) *Ollama {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	uri, _ := url.Parse(server.URL)
+	return New(*uri, opts...)
+}
+
 func TestGenerateStream(t *testing.T) {
 	streamedResponses := make([]GenerateResponse, 0)
 	resp, err := LLM.Generate(
@@ -181,3 +195,81 @@ func TestModelsActions(t *testing.T) {
 		return
 	}
 }
+
+func TestWithContextCancelMidStream(t *testing.T) {
+	release := make(chan struct{})
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response":"Hel","done":false}` + "\n"))
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	t.Cleanup(func() { close(release) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The request is cancelled once the first response is received
+	var streamErr error
+	res, err := llm.WithContext(ctx).Generate(llm.Generate.WithStream(true, 512000, func(r *GenerateResponse, err error) {
+		if err != nil {
+			streamErr = err
+			return
+		}
+		cancel()
+	}))
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ctx.Err(), got %v", err)
+	}
+	if !errors.Is(streamErr, context.Canceled) {
+		t.Errorf("expected the stream function to be called with ctx.Err(), got %v", streamErr)
+	}
+	if res == nil || res.Response != "Hel" {
+		t.Errorf("expected the partial response, got %+v", res)
+	}
+
+	// The client without a context is not affected
+	if llm.ctx != context.Background() {
+		t.Error("expected WithContext to return a copy of the client")
+	}
+}
+
+func TestWithContextCancelBeforeHeaders(t *testing.T) {
+	release := make(chan struct{})
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	t.Cleanup(func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	chatId := "cancelled"
+	res, err := llm.WithContext(ctx).Chat(&chatId,
+		llm.Chat.WithMessage(Message{Role: pointer("user"), Content: pointer("hi")}),
+		llm.Chat.WithStream(true, 512000, nil),
+	)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ctx.Err(), got %v", err)
+	}
+	if res != nil {
+		t.Errorf("expected no response, got %+v", res)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected the request to be aborted by the context, took %s", time.Since(start))
+	}
+
+	// The turn of a failed request is not stored
+	if chat, err := llm.GetChat(chatId); err != nil || (chat != nil && len(chat.Messages) != 0) {
+		t.Errorf("expected the chat to be left untouched, got %+v, %v", chat, err)
+	}
+}
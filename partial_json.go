@@ -0,0 +1,226 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// PartialJSON decodes a JSON document that is streamed in fragments, such as the deltas of a streamed
+// response with the "json" format or a schema. After each fragment, the incomplete document is closed at the
+// last point where it can be, and decoded into a best-effort, partially populated value of T.
+// Strings are decoded as far as they have been received, while incomplete numbers, literals and keys are left out.
+//
+// Example:
+//
+//	p := ollama.NewPartialJSON[City]()
+//	_, err := LLM.Generate(
+//		LLM.Generate.WithFormatSchema(*schema),
+//		LLM.Generate.WithStream(true, 512000, func(r *ollama.GenerateResponse, err error) {
+//			if city, err := p.Feed(r.Response); err == nil {
+//				render(city)
+//			}
+//		}),
+//	)
+type PartialJSON[T any] struct {
+	buf strings.Builder
+}
+
+// NewPartialJSON creates a new PartialJSON for the type T.
+func NewPartialJSON[T any]() *PartialJSON[T] {
+	return &PartialJSON[T]{}
+}
+
+// Feed appends a fragment of the document and returns the value decoded so far.
+// An error is returned if the received part of the document is not valid JSON or does not match T,
+// along with the fields that could be decoded.
+//
+// Parameters:
+//   - delta: The next fragment of the document.
+func (p *PartialJSON[T]) Feed(delta string) (*T, error) {
+	p.buf.WriteString(delta)
+	return p.Value()
+}
+
+// Value returns the value decoded from the fragments received so far.
+func (p *PartialJSON[T]) Value() (*T, error) {
+	v := new(T)
+
+	doc := completeJSON(p.buf.String())
+	if len(doc) == 0 {
+		return v, nil
+	}
+
+	err := json2.Unmarshal([]byte(doc), v)
+	return v, err
+}
+
+// Raw returns the fragments received so far.
+func (p *PartialJSON[T]) Raw() string {
+	return p.buf.String()
+}
+
+// partialFrame is an open object or array of a partial JSON document.
+type partialFrame struct {
+	object bool
+	key    bool // In an object, whether the next string is a key.
+}
+
+// completeJSON closes a truncated JSON document at the last point where it can be closed.
+// It returns an empty string if no value can be decoded yet. Invalid documents are returned as is,
+// so that decoding them reports the error.
+func completeJSON(s string) string {
+	var stack []partialFrame
+
+	closers := func() string {
+		var b strings.Builder
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].object {
+				b.WriteByte('}')
+			} else {
+				b.WriteByte(']')
+			}
+		}
+		return b.String()
+	}
+
+	// The longest prefix of the document that can be closed, and its closers
+	safe, safeClosers := 0, ""
+	mark := func(i int) {
+		safe, safeClosers = i, closers()
+	}
+
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '{' || c == '[':
+			stack = append(stack, partialFrame{object: c == '{', key: c == '{'})
+			i++
+			mark(i)
+		case c == '}' || c == ']':
+			if len(stack) == 0 || stack[len(stack)-1].object != (c == '}') {
+				return s
+			}
+			stack = stack[:len(stack)-1]
+			i++
+			mark(i)
+		case c == ':':
+			i++
+		case c == ',':
+			if len(stack) != 0 && stack[len(stack)-1].object {
+				stack[len(stack)-1].key = true
+			}
+			i++
+		case c == '"':
+			isKey := len(stack) != 0 && stack[len(stack)-1].object && stack[len(stack)-1].key
+
+			end := stringEnd(s, i+1)
+			if end < 0 {
+				if isKey {
+					return closeAt(s, safe, safeClosers)
+				}
+				// A partial string value is closed where it was cut
+				return s[:partialStringEnd(s, i+1)] + "\"" + closers()
+			}
+
+			i = end + 1
+			if isKey {
+				stack[len(stack)-1].key = false
+			} else {
+				mark(i)
+			}
+		default:
+			start := i
+			for i < len(s) && strings.IndexByte("+-.0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", s[i]) >= 0 {
+				i++
+			}
+			if i == start {
+				return s
+			}
+
+			if i < len(s) {
+				mark(i)
+				continue
+			}
+
+			// The token may be cut. A number can still grow, so it is left out until the byte after it
+			// is received, unless it is the whole document, which is trimmed to its last digit.
+			token := s[start:]
+			if token == "true" || token == "false" || token == "null" {
+				return s + closers()
+			}
+			if len(stack) == 0 && (token[0] == '-' || (token[0] >= '0' && token[0] <= '9')) {
+				token = strings.TrimRight(token, "+-.eE")
+				if json2.Valid([]byte(token)) {
+					return s[:start] + token + closers()
+				}
+			}
+			return closeAt(s, safe, safeClosers)
+		}
+	}
+
+	return closeAt(s, safe, safeClosers)
+}
+
+// closeAt closes the document after its first n bytes, or returns an empty string if nothing was received.
+func closeAt(s string, n int, closers string) string {
+	if n == 0 {
+		return ""
+	}
+	return s[:n] + closers
+}
+
+// stringEnd returns the index of the quote that ends the string starting at i, or -1 if the string is cut.
+func stringEnd(s string, i int) int {
+	for i < len(s) {
+		switch s[i] {
+		case '\\':
+			i += 2
+		case '"':
+			return i
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// partialStringEnd returns the end of the received part of a cut string starting at i,
+// without an incomplete escape sequence or UTF-8 character.
+func partialStringEnd(s string, i int) int {
+	begin, end := i, i
+	for i < len(s) {
+		if s[i] != '\\' {
+			i++
+			end = i
+			continue
+		}
+
+		n := 2
+		if i+1 < len(s) && s[i+1] == 'u' {
+			n = 6
+		}
+		if i+n > len(s) {
+			break
+		}
+		i += n
+		end = i
+	}
+
+	// Drop an incomplete UTF-8 character
+	if end == begin {
+		return end
+	}
+
+	start := end - 1
+	for start > begin && end-start < utf8.UTFMax && !utf8.RuneStart(s[start]) {
+		start--
+	}
+	if !utf8.FullRuneInString(s[start:end]) {
+		end = start
+	}
+	return end
+}
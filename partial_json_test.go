@@ -0,0 +1,99 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCompleteJSON(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{``, ``},
+		{`  `, ``},
+		{`{`, `{}`},
+		{`{"na`, `{}`},
+		{`{"name"`, `{}`},
+		{`{"name":`, `{}`},
+		{`{"name":"Pa`, `{"name":"Pa"}`},
+		{`{"name":"Pa\`, `{"name":"Pa"}`},
+		{`{"name":"Pa\u00`, `{"name":"Pa"}`},
+		{`{"name":"Paé`, `{"name":"Paé"}`},
+		{"{\"name\":\"Pa\xc3", `{"name":"Pa"}`},
+		{`{"name":"Paris",`, `{"name":"Paris"}`},
+		{`{"name":"Paris","population":21`, `{"name":"Paris"}`},
+		{`{"name":"Paris","population":-`, `{"name":"Paris"}`},
+		{`{"name":"Paris","population":2.`, `{"name":"Paris"}`},
+		{`{"name":"Paris","population":2e`, `{"name":"Paris"}`},
+		{`{"capital":tr`, `{}`},
+		{`{"capital":true`, `{"capital":true}`},
+		{`{"landmarks":[`, `{"landmarks":[]}`},
+		{`{"landmarks":["Eiffel",`, `{"landmarks":["Eiffel"]}`},
+		{`{"landmarks":["Eiffel","Lou`, `{"landmarks":["Eiffel","Lou"]}`},
+		{`{"landmarks":[{"name":"Eiffel"}],"name":"Paris"}`, `{"landmarks":[{"name":"Eiffel"}],"name":"Paris"}`},
+		{`[1,2,{"a":[`, `[1,2,{"a":[]}]`},
+		{`"hel`, `"hel"`},
+		{`21`, `21`},
+		{`-2.`, `-2`},
+		{`-`, ``},
+	}
+
+	for _, test := range tests {
+		out := completeJSON(test.in)
+		if out != test.out {
+			t.Errorf("completeJSON(%q) = %q, expected %q", test.in, out, test.out)
+		}
+
+		if len(out) != 0 && !json2.Valid([]byte(out)) {
+			t.Errorf("completeJSON(%q) = %q is not valid JSON", test.in, out)
+		}
+	}
+
+	doc := `{"a": [1, -2.5e3, true, null, {"b": "x\"y\u00e9z"}], "c": {"d": [], "e": {}}, "f": false}`
+	for i := range doc {
+		if out := completeJSON(doc[:i]); len(out) != 0 && !json2.Valid([]byte(out)) {
+			t.Errorf("completeJSON(%q) = %q is not valid JSON", doc[:i], out)
+		}
+	}
+}
+
+func TestPartialJSON(t *testing.T) {
+	doc := `{"name": "Paris", "population": 2102650, "landmarks": ["Eiffel Tower", "Louvre"]}`
+
+	p := NewPartialJSON[cityInfo]()
+	var values []cityInfo
+	for i := 0; i < len(doc); i += 7 {
+		end := i + 7
+		if end > len(doc) {
+			end = len(doc)
+		}
+
+		v, err := p.Feed(doc[i:end])
+		if err != nil {
+			t.Fatalf("Feed returned an error after %q: %s", p.Raw(), err)
+		}
+		values = append(values, *v)
+	}
+
+	// Each value must extend the previous one
+	for i := 1; i < len(values); i++ {
+		prev, cur := values[i-1], values[i]
+		if len(cur.Name) < len(prev.Name) || cur.Population < prev.Population || len(cur.Landmarks) < len(prev.Landmarks) {
+			t.Errorf("Value %d went backwards: %+v after %+v", i, cur, prev)
+		}
+	}
+
+	if values[2].Name != "Paris" || values[2].Population != 0 {
+		t.Errorf("Expected the name to be decoded before the population, got %+v", values[2])
+	}
+
+	expected := cityInfo{Name: "Paris", Population: 2102650, Landmarks: []string{"Eiffel Tower", "Louvre"}}
+	if !reflect.DeepEqual(values[len(values)-1], expected) {
+		t.Errorf("Unexpected final value: %+v", values[len(values)-1])
+	}
+
+	if _, err := NewPartialJSON[cityInfo]().Feed(`{"name": 12,`); err == nil {
+		t.Errorf("Expected an error for a value that does not match the type")
+	}
+}
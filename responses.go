@@ -2,14 +2,18 @@ package ollama
 
 import "time"
 
+// DoneReasonClientAbort is the done reason of a response whose stream was stopped with ErrStopStream.
+const DoneReasonClientAbort = "client_abort"
+
 // GenerateResponse represents the API response for "generate" endpoint.
 type GenerateResponse struct {
-	Model      string `json:"model"`       // Is the model name that generated the response.
-	CreatedAt  string `json:"created_at"`  // Is the timestamp of the response.
-	Response   string `json:"response"`    // Is the textual response itself.
-	Done       bool   `json:"done"`        // Specifies if the response is complete.
-	DoneReason string `json:"done_reason"` // The reason the model stopped generating text.
-	Context    []int  `json:"context"`     // Is an encoding of the conversation used in this response; this can be sent in the next request to keep a conversational memory.
+	Model      string `json:"model"`              // Is the model name that generated the response.
+	CreatedAt  string `json:"created_at"`         // Is the timestamp of the response.
+	Response   string `json:"response"`           // Is the textual response itself.
+	Thinking   string `json:"thinking,omitempty"` // Is the reasoning of the model before the response, if thinking was enabled.
+	Done       bool   `json:"done"`               // Specifies if the response is complete.
+	DoneReason string `json:"done_reason"`        // The reason the model stopped generating text.
+	Context    []int  `json:"context"`            // Is an encoding of the conversation used in this response; this can be sent in the next request to keep a conversational memory.
 
 	Metrics
 }
@@ -59,13 +63,14 @@ type PushPullModelResponse struct {
 
 // ShowModelInfoResponse represents the response for showing model information.
 type ShowModelInfoResponse struct {
-	License    string       `json:"license"`
-	Modelfile  string       `json:"modelfile"`
-	Parameters string       `json:"parameters"`
-	Template   string       `json:"template"`
-	System     string       `json:"system"`
-	Details    ModelDetails `json:"details"`
-	Messages   []Message    `json:"messages"`
+	License    string                 `json:"license"`
+	Modelfile  string                 `json:"modelfile"`
+	Parameters string                 `json:"parameters"`
+	Template   string                 `json:"template"`
+	System     string                 `json:"system"`
+	Details    ModelDetails           `json:"details"`
+	Messages   []Message              `json:"messages"`
+	ModelInfo  map[string]interface{} `json:"model_info"`
 }
 
 // StatusResponse represents the API response for endpoint that return status updates.
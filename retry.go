@@ -0,0 +1,179 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail with a transient error are retried.
+// It applies to the idempotent calls (Models.List, Models.ShowInfo, Blobs.Check and GenerateEmbeddings),
+// and to the streaming calls only until their response has started, so a response is never received twice.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. Defaults to 500ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts, including the delay requested by a Retry-After header. Defaults to 30s.
+	MaxBackoff time.Duration
+
+	// Multiplier increases the delay after each retry. Defaults to 2.
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to this fraction, in both directions, to spread the retries of many clients.
+	Jitter float64
+
+	// RetryableStatusCodes are the status codes of the API errors that are retried.
+	// Defaults to 408, 429, 502, 503 and 504.
+	RetryableStatusCodes []int
+
+	// Retryable overrides the default decision of whether an error is retried.
+	// By default, the API errors with a retryable status code and the network errors are retried.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy is a retry policy suitable for most applications.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+var defaultRetryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retryable reports whether the request that failed with err should be retried.
+func (p *RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		codes := p.RetryableStatusCodes
+		if codes == nil {
+			codes = defaultRetryableStatusCodes
+		}
+
+		for _, code := range codes {
+			if apiErr.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.As(err, &netErr)
+}
+
+// backoff returns the delay before the specified retry, starting at 1.
+func (p *RetryPolicy) backoff(retry int, err error) time.Duration {
+	initial, max, multiplier := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if apiErr.RetryAfter > max {
+			return max
+		}
+		return apiErr.RetryAfter
+	}
+
+	d := float64(initial) * math.Pow(multiplier, float64(retry-1))
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+
+	// The cap applies after the jitter, so the delay never exceeds it
+	if d > float64(max) {
+		d = float64(max)
+	}
+	return time.Duration(d)
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, either in seconds or as an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if len(v) == 0 {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// retry calls fn until it succeeds, fails with an error that is not retryable,
+// or the attempts of the retry policy are exhausted. It returns the last error.
+func (o *Ollama) retry(ctx context.Context, fn func() error) error {
+	p := *o.retryPolicy
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// requestWithRetry performs an idempotent request and reads its response body, retrying on transient errors.
+func (o *Ollama) requestWithRetry(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	var res []byte
+	err := o.retry(ctx, func() error {
+		resp, err := o.request(ctx, method, path, body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		res, err = io.ReadAll(resp.Body)
+		return err
+	})
+
+	return res, err
+}
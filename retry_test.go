@@ -0,0 +1,135 @@
+package ollama
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var requests int32
+	failures := int32(2)
+	status := http.StatusServiceUnavailable
+
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if n <= failures {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"loading model"}`))
+			return
+		}
+
+		if r.URL.Path == "/api/generate" {
+			w.Write([]byte(`{"response":"Hello","done":true}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"models":[]}`))
+	})
+
+	// Retries are disabled by default
+	if _, err := llm.Models.List(); !errors.Is(err, ErrServerOverloaded) {
+		t.Fatalf("Expected the error of the first attempt, got %v", err)
+	}
+
+	var apiErr *APIError
+	if _, err := llm.Models.List(); !errors.As(err, &apiErr) || apiErr.RetryAfter != 120*time.Second {
+		t.Errorf("Expected the Retry-After delay in the error, got %v", err)
+	}
+
+	llm.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Jitter: 0.5})
+
+	requests = 0
+	start := time.Now()
+	if _, err := llm.Models.List(); err != nil {
+		t.Fatalf("Expected the request to be retried, got %v", err)
+	}
+	if requests != 3 || time.Since(start) > time.Second {
+		t.Errorf("Expected 3 attempts within MaxBackoff, got %d in %s", requests, time.Since(start))
+	}
+
+	// The policy is shared with the copies of the client
+	requests = 0
+	if _, err := llm.WithContext(llm.ctx).Generate(llm.Generate.WithStream(true, 512, nil)); err != nil {
+		t.Fatalf("Expected the stream to be retried, got %v", err)
+	}
+
+	requests, failures = 0, 5
+	if _, err := llm.Models.List(); !errors.Is(err, ErrServerOverloaded) || requests != 3 {
+		t.Errorf("Expected the error after 3 attempts, got %v after %d", err, requests)
+	}
+
+	requests, status = 0, http.StatusNotFound
+	if _, err := llm.Models.List(); err == nil || requests != 1 {
+		t.Errorf("Expected a 404 to not be retried, got %d attempts", requests)
+	}
+
+	requests, status = 0, http.StatusServiceUnavailable
+	if err := llm.Models.Copy("a", "b"); err == nil || requests != 1 {
+		t.Errorf("Expected a non-idempotent request to not be retried, got %d attempts", requests)
+	}
+}
+
+func TestRetryStreamBeforeFirstByte(t *testing.T) {
+	var requests int32
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// The response starts, then the connection is lost before any data
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+
+		w.Write([]byte(`{"message":{"role":"assistant","content":"Hello"},"done":true}` + "\n"))
+	})
+	llm.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+	chunks := 0
+	res, err := llm.Chat(nil, llm.Chat.WithStream(true, 512, func(r *ChatResponse, err error) {
+		chunks++
+	}))
+	if err != nil {
+		t.Fatalf("Expected the stream to be retried, got %v", err)
+	}
+
+	if requests != 2 || chunks != 1 || *res.Message.Content != "Hello" {
+		t.Errorf("Unexpected response after %d attempts: %+v", requests, res)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 4 * time.Second, Multiplier: 2, Jitter: 0.5}
+
+	for retry := 1; retry <= 5; retry++ {
+		for i := 0; i < 100; i++ {
+			if d := p.backoff(retry, nil); d < 500*time.Millisecond || d > p.MaxBackoff {
+				t.Fatalf("Expected the delay of retry %d to be within [500ms, %s], got %s", retry, p.MaxBackoff, d)
+			}
+		}
+	}
+
+	p.Jitter = 0
+	for retry, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if d := p.backoff(retry+1, nil); d != expected {
+			t.Errorf("Expected the delay of retry %d to be %s, got %s", retry+1, expected, d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("3"); d != 3*time.Second {
+		t.Errorf("Expected 3s, got %s", d)
+	}
+
+	if d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); d < 58*time.Second || d > time.Minute {
+		t.Errorf("Expected about a minute, got %s", d)
+	}
+
+	if d := parseRetryAfter("soon"); d != 0 {
+		t.Errorf("Expected no delay, got %s", d)
+	}
+}
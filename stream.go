@@ -0,0 +1,227 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	json2 "encoding/json"
+	"fmt"
+	"io"
+)
+
+// StreamDecodeError is returned when a line of a streamed response is not a valid JSON object.
+type StreamDecodeError struct {
+	Line []byte // The offending bytes, without the trailing newline.
+	Err  error  // The underlying JSON syntax error.
+}
+
+func (e *StreamDecodeError) Error() string {
+	line := e.Line
+	if len(line) > 256 {
+		line = line[:256]
+	}
+
+	return fmt.Sprintf("malformed stream line: %s: %q", e.Err, line)
+}
+
+func (e *StreamDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// streamDecoder reads newline delimited JSON (NDJSON) objects from a response body.
+// Objects may be of any size and may span any number of reads;
+// the buffer size only controls how much is read from the body at once.
+type streamDecoder struct {
+	r *bufio.Reader
+}
+
+func newStreamDecoder(r io.Reader, bufferSize int) *streamDecoder {
+	return &streamDecoder{r: bufio.NewReaderSize(r, bufferSize)}
+}
+
+// Next returns the next JSON object of the stream.
+// It returns io.EOF when the stream has ended and a *StreamDecodeError if a line is malformed.
+func (d *streamDecoder) Next() ([]byte, error) {
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) != 0 {
+			var raw json2.RawMessage
+			if jsonErr := json2.Unmarshal(line, &raw); jsonErr != nil {
+				return nil, &StreamDecodeError{Line: line, Err: jsonErr}
+			}
+
+			return line, nil
+		}
+
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+	}
+}
+
+// Stream reads the responses of a streaming request one at a time.
+// It must be closed when no longer needed, which releases the underlying connection.
+//
+// Example:
+//
+//	s, err := llm.ChatStream(nil, llm.Chat.WithModel("llama3"), llm.Chat.WithMessage(m))
+//	if err != nil {
+//		return err
+//	}
+//	defer s.Close()
+//
+//	for s.Next() {
+//		fmt.Print(*s.Current().Message.Content)
+//	}
+//	return s.Err()
+type Stream[T any] struct {
+	ctx        context.Context
+	body       io.ReadCloser
+	decoder    *streamDecoder
+	method     string
+	path       string
+	statusCode int
+
+	current *T
+	err     error
+	done    bool
+	closed  bool
+
+	onChunk func(r *T)
+	onEnd   func(err error) error // Called when the stream ends; its result becomes the error of the stream.
+	onClose func()
+}
+
+// openStream performs a request and returns a Stream that decodes the response body into T.
+func openStream[T any](o *Ollama, ctx context.Context, method, path string, data interface{}, bufferSize int) (*Stream[T], error) {
+	jsonData, err := json2.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var s *Stream[T]
+	err = o.retry(ctx, func() error {
+		resp, err := o.request(ctx, method, path, jsonData)
+		if err != nil {
+			return err
+		}
+
+		decoder := newStreamDecoder(resp.Body, bufferSize)
+
+		// With retries, wait for the response to start, so a connection that fails before any data can be retried
+		if o.retryPolicy.MaxAttempts > 1 {
+			if _, err := decoder.r.Peek(1); err != nil && err != io.EOF {
+				resp.Body.Close()
+				return err
+			}
+		}
+
+		s = &Stream[T]{
+			ctx:        ctx,
+			body:       resp.Body,
+			decoder:    decoder,
+			method:     method,
+			path:       path,
+			statusCode: resp.StatusCode,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Next advances the stream to the next response, which is then available through Current.
+// It returns false when the stream ends, either because it was completed, failed or was closed.
+// Err reports the failure, if any.
+func (s *Stream[T]) Next() bool {
+	if s.done {
+		return false
+	}
+
+	if s.ctx.Err() != nil {
+		s.end(s.ctx.Err())
+		return false
+	}
+
+	chunk, err := s.decoder.Next()
+	if err == io.EOF {
+		s.end(nil)
+		return false
+	}
+
+	if err != nil {
+		if s.ctx.Err() != nil {
+			err = s.ctx.Err()
+		}
+		s.end(err)
+		return false
+	}
+
+	if apiErr := newStreamError(s.method, s.path, s.statusCode, chunk); apiErr != nil {
+		s.end(apiErr)
+		return false
+	}
+
+	r, err := bodyTo[T](chunk)
+	if err != nil {
+		s.end(err)
+		return false
+	}
+
+	s.current = r
+	if s.onChunk != nil {
+		s.onChunk(r)
+	}
+
+	return true
+}
+
+// Current returns the response read by the latest call to Next.
+func (s *Stream[T]) Current() *T {
+	return s.current
+}
+
+// Err returns the error that ended the stream, or nil if the stream was completed or closed.
+func (s *Stream[T]) Err() error {
+	return s.err
+}
+
+// Close stops reading the stream and closes the underlying connection.
+// It is safe to call Close multiple times and after the stream has ended.
+func (s *Stream[T]) Close() error {
+	s.done = true
+	return s.release()
+}
+
+// end is called when the stream was completed or failed.
+func (s *Stream[T]) end(err error) {
+	s.done = true
+	s.err = err
+
+	if s.onEnd != nil {
+		s.err = s.onEnd(err)
+	}
+	s.release()
+}
+
+// release closes the body and calls the close hook, only the first time it is called.
+func (s *Stream[T]) release() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	err := s.body.Close()
+	if s.onClose != nil {
+		s.onClose()
+	}
+	return err
+}
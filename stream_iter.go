@@ -0,0 +1,33 @@
+//go:build go1.23
+
+package ollama
+
+import "iter"
+
+// All returns an iterator over the responses of the stream, for use with range.
+// If the stream fails, the last iteration yields the error.
+// The stream is closed when the loop ends, including when it is stopped early with break.
+//
+// Example:
+//
+//	for r, err := range s.All() {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(r.Response)
+//	}
+func (s *Stream[T]) All() iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		defer s.Close()
+
+		for s.Next() {
+			if !yield(s.Current(), nil) {
+				return
+			}
+		}
+
+		if err := s.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
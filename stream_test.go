@@ -0,0 +1,239 @@
+package ollama
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var recordedStreams = []string{
+	"chat_stream.ndjson",
+	"generate_stream.ndjson",
+	"pull_stream.ndjson",
+}
+
+// chunkReader returns at most n bytes on each read, to simulate objects straddling read boundaries.
+type chunkReader struct {
+	data []byte
+	n    int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+
+	n := r.n
+	if n > len(p) {
+		n = len(p)
+	}
+	if n > len(r.data) {
+		n = len(r.data)
+	}
+
+	copy(p, r.data[:n])
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func decodeAll(r io.Reader, bufferSize int) ([][]byte, error) {
+	var res [][]byte
+	decoder := newStreamDecoder(r, bufferSize)
+	for {
+		chunk, err := decoder.Next()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return res, err
+		}
+		res = append(res, chunk)
+	}
+}
+
+func readRecordedStream(t testing.TB, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read recorded stream: %s", err)
+	}
+	return data
+}
+
+func TestStreamDecoderRecorded(t *testing.T) {
+	for _, name := range recordedStreams {
+		data := readRecordedStream(t, name)
+		lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+
+		for _, size := range []int{1, 7, 16, 64, 512000} {
+			objects, err := decodeAll(&chunkReader{data: data, n: size}, size)
+			if err != nil {
+				t.Errorf("%s: decoding with chunk size %d returned an error: %s", name, size, err)
+				continue
+			}
+
+			if len(objects) != len(lines) {
+				t.Errorf("%s: expected %d objects with chunk size %d, got %d", name, len(lines), size, len(objects))
+				continue
+			}
+
+			for i := range lines {
+				if !bytes.Equal(objects[i], lines[i]) {
+					t.Errorf("%s: expected object %q, got %q", name, lines[i], objects[i])
+				}
+			}
+		}
+	}
+}
+
+func TestStreamDecoderMalformedLine(t *testing.T) {
+	data := []byte("{\"status\":\"ok\"}\n{\"status\":\"broken\"\n{\"status\":\"ok\"}\n")
+
+	objects, err := decodeAll(bytes.NewReader(data), 16)
+
+	var decodeErr *StreamDecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected a StreamDecodeError, got %v", err)
+	}
+
+	if string(decodeErr.Line) != "{\"status\":\"broken\"" {
+		t.Errorf("Expected the offending line, got %q", decodeErr.Line)
+	}
+
+	if len(objects) != 1 {
+		t.Errorf("Expected 1 object before the malformed line, got %d", len(objects))
+	}
+}
+
+func FuzzStreamDecoder(f *testing.F) {
+	for _, name := range recordedStreams {
+		data := readRecordedStream(f, name)
+		f.Add(data, uint8(1))
+		f.Add(data, uint8(13))
+		f.Add(data, uint8(255))
+	}
+	f.Add([]byte("{\"a\":\"\\\\\"}\n}\n"), uint8(3))
+	f.Add([]byte("\n\n  {}\r\n{\"a\":1}"), uint8(2))
+
+	f.Fuzz(func(t *testing.T, data []byte, size uint8) {
+		chunk := int(size)
+		if chunk == 0 {
+			chunk = 1
+		}
+
+		expected, expectedErr := decodeAll(bytes.NewReader(data), len(data)+16)
+		actual, actualErr := decodeAll(&chunkReader{data: data, n: chunk}, chunk)
+
+		if (expectedErr == nil) != (actualErr == nil) {
+			t.Fatalf("read size changed the result: %v != %v", expectedErr, actualErr)
+		}
+
+		if expectedErr != nil {
+			var decodeErr *StreamDecodeError
+			if !errors.As(actualErr, &decodeErr) {
+				t.Fatalf("Expected a StreamDecodeError, got %v", actualErr)
+			}
+			if !bytes.Contains(data, decodeErr.Line) {
+				t.Fatalf("offending line %q is not part of the input", decodeErr.Line)
+			}
+		}
+
+		if len(expected) != len(actual) {
+			t.Fatalf("read size changed the number of objects: %d != %d", len(expected), len(actual))
+		}
+
+		for i := range expected {
+			if !bytes.Equal(expected[i], actual[i]) {
+				t.Fatalf("read size changed object %d: %q != %q", i, expected[i], actual[i])
+			}
+		}
+	})
+}
+
+func TestChatStreamIterator(t *testing.T) {
+	data := readRecordedStream(t, "chat_stream.ndjson")
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+
+	chatId := "stream"
+	s, err := llm.ChatStream(&chatId, llm.Chat.WithModel("llama3"))
+	if err != nil {
+		t.Fatalf("ChatStream returned an error: %s", err)
+	}
+	defer s.Close()
+
+	result := ""
+	for s.Next() {
+		result += *s.Current().Message.Content
+	}
+
+	if s.Err() != nil {
+		t.Fatalf("Stream returned an error: %s", s.Err())
+	}
+
+	chat := mustGetChat(t, llm, chatId)
+	if chat == nil || len(chat.Messages) != 1 || *chat.Messages[0].Content != result {
+		t.Errorf("Expected the streamed reply to be added to the chat, got %+v", chat)
+	}
+
+	// Stopping early must not add the reply to the chat.
+	s, err = llm.ChatStream(&chatId, llm.Chat.WithModel("llama3"))
+	if err != nil {
+		t.Fatalf("ChatStream returned an error: %s", err)
+	}
+
+	if !s.Next() {
+		t.Fatalf("Expected at least one response, got error %v", s.Err())
+	}
+	s.Close()
+
+	if s.Next() || s.Err() != nil {
+		t.Errorf("Expected a closed stream to end without an error")
+	}
+
+	if len(mustGetChat(t, llm, chatId).Messages) != 1 {
+		t.Errorf("Expected a closed stream to not modify the chat")
+	}
+}
+
+func TestChatStreamHandlerStop(t *testing.T) {
+	data := readRecordedStream(t, "chat_stream.ndjson")
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+
+	chatId := "abort"
+	chunks := 0
+	resp, err := llm.Chat(
+		&chatId,
+		llm.Chat.WithModel("llama3"),
+		llm.Chat.WithStreamHandler(16, func(r *ChatResponse, err error) error {
+			chunks++
+			if chunks == 2 {
+				return ErrStopStream
+			}
+			return nil
+		}),
+	)
+
+	if err != nil {
+		t.Fatalf("Chat returned an error: %s", err)
+	}
+
+	if chunks != 2 {
+		t.Errorf("Expected the handler to be called 2 times, got %d", chunks)
+	}
+
+	if *resp.Message.Content != "Here is" || resp.DoneReason != DoneReasonClientAbort || !resp.Done {
+		t.Errorf("Unexpected aborted response: %q, %q", *resp.Message.Content, resp.DoneReason)
+	}
+
+	chat := mustGetChat(t, llm, chatId)
+	if len(chat.Messages) != 1 || *chat.Messages[0].Content != "Here is" {
+		t.Errorf("Expected the partial reply to be added to the chat, got %+v", chat.Messages)
+	}
+}
@@ -0,0 +1,322 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ResponseFormat is the format of the response of a request, either "json" or a JSON schema.
+type ResponseFormat struct {
+	Type   string      // The format, currently only "json". Ignored if Schema is set.
+	Schema *JSONSchema // The JSON schema the response must conform to.
+}
+
+// MarshalJSON encodes the format as the "json" string or as the schema object.
+func (f ResponseFormat) MarshalJSON() ([]byte, error) {
+	if f.Schema != nil {
+		return json2.Marshal(f.Schema)
+	}
+	return json2.Marshal(f.Type)
+}
+
+// UnmarshalJSON decodes either a format string or a schema object.
+func (f *ResponseFormat) UnmarshalJSON(data []byte) error {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "\"") {
+		f.Schema = nil
+		return json2.Unmarshal(data, &f.Type)
+	}
+
+	f.Type = ""
+	f.Schema = &JSONSchema{}
+	return json2.Unmarshal(data, f.Schema)
+}
+
+// SchemaOf derives the JSON schema of the Go type T, following the same rules as RegisterTool.
+func SchemaOf[T any]() (*JSONSchema, error) {
+	return schemaOf(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// DecodeError is returned when the response of the model does not conform to the requested type.
+type DecodeError struct {
+	Raw string // The text of the response.
+	Err error  // The JSON or schema validation error.
+}
+
+func (e *DecodeError) Error() string {
+	raw := e.Raw
+	if len(raw) > 256 {
+		raw = raw[:256]
+	}
+
+	return fmt.Sprintf("decode structured output: %s: %q", e.Err, raw)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeInto decodes the text of a response into T, checking that it conforms to the schema.
+func decodeInto[T any](schema *JSONSchema, raw string) (*T, error) {
+	var v interface{}
+	if err := json2.Unmarshal([]byte(raw), &v); err != nil {
+		return nil, &DecodeError{Raw: raw, Err: err}
+	}
+
+	if errs := validateSchema(schema, v, ""); len(errs) != 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, err := range errs {
+			msgs = append(msgs, err.String())
+		}
+		return nil, &DecodeError{Raw: raw, Err: errors.New("does not match the schema: " + strings.Join(msgs, "; "))}
+	}
+
+	res, err := bodyTo[T]([]byte(raw))
+	if err != nil {
+		return nil, &DecodeError{Raw: raw, Err: err}
+	}
+	return res, nil
+}
+
+// DefaultValidationRetryPrompt is the instruction sent after an invalid structured response, followed by the error.
+const DefaultValidationRetryPrompt = "Your previous response is not valid JSON matching the expected schema. " +
+	"Respond again with only the corrected JSON."
+
+// defaultTemperature is the temperature of Ollama, assumed when lowering the temperature of a request without one.
+const defaultTemperature = 0.8
+
+// ValidationRetry retries GenerateInto and ChatInto when the response does not conform to the expected type.
+// Each retry re-prompts the model with the invalid response and the validation error.
+type ValidationRetry struct {
+	// MaxAttempts is the maximum number of requests, including the first one.
+	MaxAttempts int `json:"max_attempts"`
+
+	// TemperatureStep lowers the temperature of the request on each retry, down to zero.
+	// If the request has no temperature, the default temperature of Ollama (0.8) is assumed.
+	TemperatureStep float64 `json:"temperature_step,omitempty"`
+
+	// Prompt is the instruction sent after an invalid response, followed by the error. Defaults to DefaultValidationRetryPrompt.
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// ValidationAttempt is a request of GenerateInto or ChatInto whose response did not conform to the expected type.
+type ValidationAttempt struct {
+	Raw         string   // The text of the response.
+	Err         error    // The *DecodeError of the response.
+	Temperature *float64 // The temperature of the request, if set.
+}
+
+// ValidationError is returned when every attempt of a ValidationRetry failed. It unwraps to the error of the last attempt.
+type ValidationError struct {
+	Attempts []ValidationAttempt // The failed attempts, in order.
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("structured output is still invalid after %d attempts: %s", len(e.Attempts), e.Unwrap())
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// retryPrompt returns the instruction sent after the failed attempt.
+func (p *ValidationRetry) retryPrompt(failed ValidationAttempt) string {
+	prompt := p.Prompt
+	if len(prompt) == 0 {
+		prompt = DefaultValidationRetryPrompt
+	}
+
+	var decodeErr *DecodeError
+	if errors.As(failed.Err, &decodeErr) {
+		return prompt + "\n\nError: " + decodeErr.Err.Error()
+	}
+	return prompt + "\n\nError: " + failed.Err.Error()
+}
+
+// temperature returns the temperature of the specified retry, or the temperature of the request if it is not lowered.
+func (p *ValidationRetry) temperature(options *Options, retry int) *float64 {
+	var t *float64
+	if options != nil {
+		t = options.Temperature
+	}
+
+	if p.TemperatureStep <= 0 || retry == 0 {
+		return t
+	}
+
+	v := defaultTemperature
+	if t != nil {
+		v = *t
+	}
+
+	v -= p.TemperatureStep * float64(retry)
+	if v < 0 {
+		v = 0
+	}
+	return &v
+}
+
+// withTemperature returns a copy of the options with the temperature.
+func withTemperature(options *Options, t *float64) *Options {
+	res := Options{}
+	if options != nil {
+		res = *options
+	}
+	res.Temperature = t
+	return &res
+}
+
+// decodeWithRetry sends the request until its response decodes into T or the attempts of the policy are exhausted.
+// send performs the specified attempt, given the failed attempts so far. The policy may be nil, in which case
+// the request is sent once and a *DecodeError is returned if it fails.
+func decodeWithRetry[T any](p *ValidationRetry, schema *JSONSchema, send func(retry int, failed []ValidationAttempt) (string, *float64, error)) (*T, error) {
+	attempts := 1
+	if p != nil && p.MaxAttempts > 1 {
+		attempts = p.MaxAttempts
+	}
+
+	failed := make([]ValidationAttempt, 0, attempts)
+	for i := 0; i < attempts; i++ {
+		raw, temperature, err := send(i, failed)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := decodeInto[T](schema, raw)
+		if err == nil {
+			return v, nil
+		}
+
+		failed = append(failed, ValidationAttempt{Raw: raw, Err: err, Temperature: temperature})
+	}
+
+	if attempts == 1 {
+		return nil, failed[0].Err
+	}
+	return nil, &ValidationError{Attempts: failed}
+}
+
+// GenerateInto generates a completion constrained to the JSON schema of T and decodes the response into T.
+// If the response does not conform to T, a *DecodeError with the raw text is returned.
+// With WithValidationRetry, the model is re-prompted with the error and a *ValidationError is returned
+// if every attempt fails.
+//
+// Parameters:
+//   - o: The client.
+//   - builder: The options of the request, as passed to Generate.
+func GenerateInto[T any](o *Ollama, builder ...func(reqBuilder *GenerateRequestBuilder)) (*T, error) {
+	schema, err := SchemaOf[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	req := GenerateRequestBuilder{}
+	o.defaults.applyGenerate(&req)
+	for _, f := range builder {
+		f(&req)
+	}
+	o.Generate.WithFormatSchema(*schema)(&req)
+
+	return decodeWithRetry[T](req.ValidationRetry, schema, func(retry int, failed []ValidationAttempt) (string, *float64, error) {
+		attempt := req
+		if retry > 0 {
+			last := failed[len(failed)-1]
+			prompt := ""
+			if req.Prompt != nil {
+				prompt = *req.Prompt
+			}
+
+			attempt.Prompt = pointer(prompt + "\n\nPrevious response:\n" + last.Raw + "\n\n" + req.ValidationRetry.retryPrompt(last))
+			attempt.Options = withTemperature(req.Options, req.ValidationRetry.temperature(req.Options, retry))
+		}
+
+		res, err := o.Generate(func(r *GenerateRequestBuilder) {
+			*r = attempt
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		var temperature *float64
+		if attempt.Options != nil {
+			temperature = attempt.Options.Temperature
+		}
+		return res.Response, temperature, nil
+	})
+}
+
+// ChatInto generates the next message of a chat constrained to the JSON schema of T and decodes it into T.
+// If the reply does not conform to T, a *DecodeError with the raw text is returned and the chat is not changed.
+// With WithValidationRetry, the model is re-prompted with the error and a *ValidationError is returned
+// if every attempt fails. Only the messages of the request and the valid reply are stored in the chat.
+//
+// Parameters:
+//   - o: The client.
+//   - chatId: The ID of the chat, or nil to not keep history.
+//   - builder: The options of the request, as passed to Chat.
+func ChatInto[T any](o *Ollama, chatId *string, builder ...func(reqBuilder *ChatRequestBuilder)) (*T, error) {
+	schema, err := SchemaOf[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	req := ChatRequestBuilder{}
+	o.defaults.applyChat(&req)
+	for _, f := range builder {
+		f(&req)
+	}
+	o.Chat.WithFormatSchema(*schema)(&req)
+
+	if req.Stream == nil {
+		req.Stream = pointer(false)
+	}
+
+	if req.StreamBufferSize == nil {
+		req.StreamBufferSize = pointer(512000)
+	}
+
+	var v *T
+	_, err = o.chatTurn(chatId, &req, func(req *ChatRequestBuilder) (*ChatResponse, error) {
+		var final *ChatResponse
+		var err error
+
+		v, err = decodeWithRetry[T](req.ValidationRetry, schema, func(retry int, failed []ValidationAttempt) (string, *float64, error) {
+			attempt := *req
+			if retry > 0 {
+				// The failed replies are only sent with the retries; they are not stored in the chat
+				attempt.Messages = append(make([]Message, 0, len(req.Messages)+2*retry), req.Messages...)
+				for _, f := range failed {
+					attempt.Messages = append(attempt.Messages,
+						Message{Role: pointer("assistant"), Content: pointer(f.Raw)},
+						Message{Role: pointer("user"), Content: pointer(req.ValidationRetry.retryPrompt(f))},
+					)
+				}
+				attempt.Options = withTemperature(req.Options, req.ValidationRetry.temperature(req.Options, retry))
+			}
+
+			if final, err = o.sendChat(&attempt); err != nil {
+				return "", nil, err
+			}
+
+			var temperature *float64
+			if attempt.Options != nil {
+				temperature = attempt.Options.Temperature
+			}
+
+			content := ""
+			if final.Message.Content != nil {
+				content = *final.Message.Content
+			}
+			return content, temperature, nil
+		})
+
+		return final, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,195 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type cityInfo struct {
+	Name       string   `json:"name"`
+	Population int      `json:"population"`
+	Landmarks  []string `json:"landmarks,omitempty"`
+}
+
+func TestResponseFormat(t *testing.T) {
+	var r GenerateRequestBuilder
+	(GenerateFunc(nil)).WithFormat("json")(&r)
+
+	data, _ := json2.Marshal(r.Format)
+	if string(data) != `"json"` {
+		t.Errorf("Expected the format to be encoded as a string, got %s", data)
+	}
+
+	schema, _ := SchemaOf[cityInfo]()
+	(GenerateFunc(nil)).WithFormatSchema(*schema)(&r)
+
+	data, _ = json2.Marshal(r.Format)
+	if string(data) != `{"type":"object","properties":{"landmarks":{"type":"array","items":{"type":"string"}},"name":{"type":"string"},"population":{"type":"integer"}},"required":["name","population"]}` {
+		t.Errorf("Expected the format to be encoded as the schema, got %s", data)
+	}
+
+	var decoded ResponseFormat
+	if err := json2.Unmarshal(data, &decoded); err != nil || decoded.Schema == nil || decoded.Schema.Properties["name"].Type != "string" {
+		t.Errorf("Expected the schema to be decoded, got %+v (%v)", decoded, err)
+	}
+}
+
+func TestGenerateInto(t *testing.T) {
+	var response string
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Format json2.RawMessage `json:"format"`
+		}
+		if err := json2.NewDecoder(r.Body).Decode(&req); err != nil || req.Format[0] != '{' {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Path == "/api/chat" {
+			json2.NewEncoder(w).Encode(ChatResponse{Message: Message{Role: pointer("assistant"), Content: &response}, Done: true})
+			return
+		}
+		json2.NewEncoder(w).Encode(GenerateResponse{Response: response, Done: true})
+	})
+
+	response = `{"name":"Paris","population":2102650,"landmarks":["Eiffel Tower"]}`
+	city, err := GenerateInto[cityInfo](llm, llm.Generate.WithPrompt("Describe Paris"))
+	if err != nil {
+		t.Fatalf("GenerateInto returned an error: %s", err)
+	}
+
+	if city.Name != "Paris" || city.Population != 2102650 || city.Landmarks[0] != "Eiffel Tower" {
+		t.Errorf("Unexpected decoded value: %+v", city)
+	}
+
+	for _, raw := range []string{`{"name":"Paris"}`, `{"name":"Paris","population":"many"}`, `The population of Paris is 2 million.`} {
+		response = raw
+
+		_, err = ChatInto[cityInfo](llm, nil, llm.Chat.WithMessage(Message{Content: pointer("Describe Paris")}))
+
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) || decodeErr.Raw != raw {
+			t.Errorf("Expected a DecodeError with the raw text for %s, got %v", raw, err)
+		}
+	}
+}
+
+func TestDecodeIntoNull(t *testing.T) {
+	tests := []struct {
+		schema   *JSONSchema
+		expected string
+	}{
+		{&JSONSchema{Type: "object"}, "expected an object, got null"},
+		{&JSONSchema{Type: "array", Items: &JSONSchema{Type: "string"}}, "expected an array, got null"},
+		{&JSONSchema{Type: "integer"}, "expected an integer, got null"},
+	}
+
+	for _, tt := range tests {
+		_, err := decodeInto[interface{}](tt.schema, "null")
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected the error %q for the type %s, got %v", tt.expected, tt.schema.Type, err)
+		}
+	}
+}
+
+func TestValidationRetry(t *testing.T) {
+	var responses []string
+	var prompts []string
+	var messages [][]Message
+	var temperatures []*float64
+
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Prompt   string    `json:"prompt"`
+			Messages []Message `json:"messages"`
+			Options  *Options  `json:"options"`
+		}
+		if err := json2.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		prompts = append(prompts, req.Prompt)
+		messages = append(messages, req.Messages)
+		if req.Options != nil {
+			temperatures = append(temperatures, req.Options.Temperature)
+		} else {
+			temperatures = append(temperatures, nil)
+		}
+
+		response := responses[0]
+		responses = responses[1:]
+
+		if r.URL.Path == "/api/chat" {
+			json2.NewEncoder(w).Encode(ChatResponse{Message: Message{Role: pointer("assistant"), Content: &response}, Done: true})
+			return
+		}
+		json2.NewEncoder(w).Encode(GenerateResponse{Response: response, Done: true})
+	})
+
+	responses = []string{`{"name":"Par`, `{"name":"Paris"}`, `{"name":"Paris","population":2102650}`}
+	city, err := GenerateInto[cityInfo](llm,
+		llm.Generate.WithPrompt("Describe Paris"),
+		llm.Generate.WithValidationRetry(ValidationRetry{MaxAttempts: 3, TemperatureStep: 0.3}),
+	)
+	if err != nil {
+		t.Fatalf("GenerateInto returned an error: %s", err)
+	}
+
+	if city.Population != 2102650 {
+		t.Errorf("Unexpected decoded value: %+v", city)
+	}
+
+	if temperatures[0] != nil || math.Abs(*temperatures[1]-0.5) > 1e-9 || math.Abs(*temperatures[2]-0.2) > 1e-9 {
+		t.Errorf("Expected the temperature to be lowered on each retry")
+	}
+
+	expected := "Describe Paris\n\nPrevious response:\n{\"name\":\"Paris\"}\n\n" + DefaultValidationRetryPrompt + "\n\nError: does not match the schema: population: is required"
+	if prompts[2] != expected {
+		t.Errorf("Unexpected retry prompt: %q", prompts[2])
+	}
+
+	// Failed attempts are reported and not stored in the chat
+	chatId := "retry"
+	responses = []string{`{}`, `[]`}
+	_, err = ChatInto[cityInfo](llm, &chatId,
+		llm.Chat.WithMessage(Message{Content: pointer("Describe Paris")}),
+		llm.Chat.WithValidationRetry(ValidationRetry{MaxAttempts: 2}),
+	)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Attempts) != 2 || validationErr.Attempts[1].Raw != `[]` {
+		t.Fatalf("Expected a ValidationError with 2 attempts, got %v", err)
+	}
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Raw != `[]` {
+		t.Errorf("Expected the ValidationError to unwrap to the last DecodeError")
+	}
+
+	if chat, _ := llm.GetChat(chatId); chat != nil {
+		t.Errorf("Expected the chat to not be changed, got %+v", chat.Messages)
+	}
+
+	responses = []string{`{}`, `{"name":"Paris","population":2102650}`}
+	_, err = ChatInto[cityInfo](llm, &chatId,
+		llm.Chat.WithMessage(Message{Content: pointer("Describe Paris")}),
+		llm.Chat.WithValidationRetry(ValidationRetry{MaxAttempts: 2}),
+	)
+	if err != nil {
+		t.Fatalf("ChatInto returned an error: %s", err)
+	}
+
+	if retry := messages[len(messages)-1]; len(retry) != 3 || *retry[1].Content != `{}` || *retry[2].Role != "user" {
+		t.Errorf("Expected the invalid reply and the error to be sent with the retry, got %+v", retry)
+	}
+
+	history := mustGetChat(t, llm, chatId).Messages
+	if len(history) != 2 || *history[1].Content != `{"name":"Paris","population":2102650}` {
+		t.Errorf("Expected only the request and the valid reply to be stored, got %+v", history)
+	}
+}
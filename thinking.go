@@ -0,0 +1,89 @@
+package ollama
+
+import "strings"
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// SplitThinking separates the <think>...</think> blocks that some reasoning models inline in their content
+// from the answer. A closing tag without an opening one is supported, for models whose template opens the block.
+// An unterminated block is returned as thinking. If the content has no blocks, it is returned as the answer unchanged.
+//
+// Parameters:
+//   - content: The content of the response.
+func SplitThinking(content string) (thinking, answer string) {
+	if !strings.Contains(content, thinkOpen) && !strings.Contains(content, thinkClose) {
+		return "", content
+	}
+
+	var t, a strings.Builder
+	addThinking := func(v string) {
+		v = strings.TrimSpace(v)
+		if len(v) == 0 {
+			return
+		}
+		if t.Len() != 0 {
+			t.WriteString("\n")
+		}
+		t.WriteString(v)
+	}
+
+	rest := content
+	if end := strings.Index(rest, thinkClose); end >= 0 && !strings.Contains(rest[:end], thinkOpen) {
+		addThinking(rest[:end])
+		rest = rest[end+len(thinkClose):]
+	}
+
+	for len(rest) != 0 {
+		start := strings.Index(rest, thinkOpen)
+		if start < 0 {
+			a.WriteString(rest)
+			break
+		}
+
+		a.WriteString(rest[:start])
+		rest = rest[start+len(thinkOpen):]
+
+		end := strings.Index(rest, thinkClose)
+		if end < 0 {
+			addThinking(rest)
+			break
+		}
+
+		addThinking(rest[:end])
+		rest = rest[end+len(thinkClose):]
+	}
+
+	return t.String(), strings.TrimLeft(a.String(), " \t\r\n")
+}
+
+// splitsThinking reports whether the inline thinking of a response is split from it. Models that inline
+// their thinking usually reject the think parameter, so it is split unless thinking is disabled.
+func splitsThinking(think *bool) bool {
+	return think == nil || *think
+}
+
+// splitMessageThinking moves the inline thinking of the message to its Thinking field,
+// unless the model returned the thinking separately.
+func splitMessageThinking(m Message) Message {
+	if (m.Thinking != nil && len(*m.Thinking) != 0) || m.Content == nil {
+		return m
+	}
+
+	thinking, answer := SplitThinking(*m.Content)
+	if len(thinking) != 0 {
+		m.Thinking = &thinking
+		m.Content = &answer
+	}
+	return m
+}
+
+// storedReply returns the reply as it is stored in the chat, without its thinking unless it is kept.
+func storedReply(req *ChatRequestBuilder, m Message) Message {
+	if req.KeepThinking == nil || !*req.KeepThinking {
+		m.Thinking = nil
+	}
+	return m
+}
@@ -0,0 +1,168 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		content, thinking, answer string
+	}{
+		{"The sky is blue.", "", "The sky is blue."},
+		{"<think>\nRayleigh scattering.\n</think>\n\nThe sky is blue.", "Rayleigh scattering.", "The sky is blue."},
+		{"Rayleigh scattering.</think>The sky is blue.", "Rayleigh scattering.", "The sky is blue."},
+		{"<think>First.</think>The sky<think>Second.</think> is blue.", "First.\nSecond.", "The sky is blue."},
+		{"<think>Still thinking", "Still thinking", ""},
+		{"<think></think>The sky is blue.", "", "The sky is blue."},
+	}
+
+	for _, test := range tests {
+		thinking, answer := SplitThinking(test.content)
+		if thinking != test.thinking || answer != test.answer {
+			t.Errorf("SplitThinking(%q) = %q, %q, expected %q, %q", test.content, thinking, answer, test.thinking, test.answer)
+		}
+	}
+}
+
+func TestChatThinking(t *testing.T) {
+	var think *bool
+	var history []Message
+	inline := false
+
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequestBuilder
+		if err := json2.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		think, history = req.Think, req.Messages
+
+		if r.URL.Path == "/api/generate" || inline {
+			// Models that inline their thinking do not support the think parameter
+			if think != nil && *think {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"model does not support thinking"}`))
+				return
+			}
+
+			w.Write([]byte(`{"response":"<think>Blue light","message":{"role":"assistant","content":"<think>Blue light"},"done":false}` + "\n"))
+			w.Write([]byte(`{"response":" scatters.</think>Blue.","message":{"role":"assistant","content":" scatters.</think>Blue."},"done":true}` + "\n"))
+			return
+		}
+
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","thinking":"Blue light"},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","thinking":" scatters."},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"Blue."},"done":true}` + "\n"))
+	})
+
+	chatId := "thinking"
+	res, err := llm.Chat(&chatId, llm.Chat.WithThink(true), llm.Chat.WithStream(true, 512, nil))
+	if err != nil {
+		t.Fatalf("Chat returned an error: %s", err)
+	}
+
+	if think == nil || !*think {
+		t.Errorf("Expected think to be sent")
+	}
+
+	if *res.Message.Thinking != "Blue light scatters." || *res.Message.Content != "Blue." {
+		t.Errorf("Expected the thinking to be aggregated, got %q, %q", *res.Message.Thinking, *res.Message.Content)
+	}
+
+	if m := mustGetChat(t, llm, chatId).Messages[0]; m.Thinking != nil {
+		t.Errorf("Expected the thinking to not be stored by default")
+	}
+
+	inline = true
+	res, err = llm.Chat(&chatId, llm.Chat.WithKeepThinking(true))
+	if err != nil {
+		t.Fatalf("Chat returned an error: %s", err)
+	}
+
+	if *res.Message.Thinking != "Blue light scatters." || *res.Message.Content != "Blue." {
+		t.Errorf("Expected the inline thinking to be split, got %q, %q", *res.Message.Thinking, *res.Message.Content)
+	}
+
+	if m := mustGetChat(t, llm, chatId).Messages[1]; m.Thinking == nil || *m.Thinking != "Blue light scatters." {
+		t.Errorf("Expected the thinking to be stored")
+	}
+
+	if _, err = llm.Chat(&chatId); err != nil {
+		t.Fatalf("Chat returned an error: %s", err)
+	}
+
+	if think != nil || history[1].Thinking == nil {
+		t.Errorf("Expected the stored thinking to be sent with the history")
+	}
+
+	gen, err := llm.Generate()
+	if err != nil {
+		t.Fatalf("Generate returned an error: %s", err)
+	}
+
+	if gen.Thinking != "Blue light scatters." || gen.Response != "Blue." {
+		t.Errorf("Expected the inline thinking to be split, got %q, %q", gen.Thinking, gen.Response)
+	}
+
+	// The response is left as is when thinking is disabled
+	gen, err = llm.Generate(llm.Generate.WithThink(false))
+	if err != nil {
+		t.Fatalf("Generate returned an error: %s", err)
+	}
+
+	if gen.Thinking != "" || gen.Response != "<think>Blue light scatters.</think>Blue." {
+		t.Errorf("Expected the inline thinking to be kept, got %q, %q", gen.Thinking, gen.Response)
+	}
+}
+
+func TestChatKeepThinkingStored(t *testing.T) {
+	dir := t.TempDir()
+
+	stores := map[string]func() (ChatStore, error){
+		"memory": func() (ChatStore, error) {
+			return NewMemoryChatStore(), nil
+		},
+		"file": func() (ChatStore, error) {
+			return NewFileChatStore(filepath.Join(dir, "chats"))
+		},
+		"jsonl": func() (ChatStore, error) {
+			return NewJSONLChatStore(filepath.Join(dir, "chats.jsonl"))
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, err := open()
+			if err != nil {
+				t.Fatalf("Failed to open store: %s", err)
+			}
+
+			llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"message":{"role":"assistant","content":"Blue.","thinking":"Blue light scatters."},"done":true}`))
+			})
+			llm.SetChatStore(store)
+
+			chat := NewChat("thinking")
+			chat.KeepThinking = true
+			if err := llm.PreloadChat(*chat); err != nil {
+				t.Fatalf("PreloadChat returned an error: %s", err)
+			}
+
+			if !mustGetChat(t, llm, "thinking").KeepThinking {
+				t.Fatalf("Expected KeepThinking to be stored")
+			}
+
+			chatId := "thinking"
+			if _, err := llm.Chat(&chatId, llm.Chat.WithThink(true), llm.Chat.WithMessage(Message{Role: pointer("user"), Content: pointer("Why?")})); err != nil {
+				t.Fatalf("Chat returned an error: %s", err)
+			}
+
+			if m := mustGetChat(t, llm, chatId).Messages[1]; m.Thinking == nil || *m.Thinking != "Blue light scatters." {
+				t.Errorf("Expected the thinking to be kept by the chat")
+			}
+		})
+	}
+}
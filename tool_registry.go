@@ -0,0 +1,481 @@
+package ollama
+
+import (
+	"context"
+	"encoding"
+	json2 "encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ToolRegistry holds Go functions that the model can call as tools.
+// The JSON schema of each tool is derived from the argument struct of its function,
+// and the arguments produced by the model are validated against it before the function is invoked.
+// The methods of ToolRegistry are safe for concurrent use.
+//
+// Example:
+//
+//	type WeatherArgs struct {
+//		City string `json:"city" description:"The name of the city"`
+//		Unit string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+//	}
+//
+//	tools := ollama.NewToolRegistry()
+//	err := ollama.RegisterTool(tools, "get_weather", "Get the current weather of a city",
+//		func(ctx context.Context, args WeatherArgs) (string, error) {
+//			return "sunny", nil
+//		})
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]*registeredTool
+}
+
+type registeredTool struct {
+	tool Tool
+	call func(ctx context.Context, args map[string]interface{}) (string, error)
+}
+
+// NewToolRegistry creates a new empty ToolRegistry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		tools: make(map[string]*registeredTool),
+	}
+}
+
+// RegisterTool adds a function to the registry as a tool.
+//
+// The arguments are described by the struct T. The name of each property is taken from the json tag of the field,
+// and the following tags are supported:
+//   - description: The description of the property.
+//   - enum: The comma separated allowed values of the property.
+//   - required: "true" or "false". By default, a property is required unless it is a pointer or tagged with omitempty.
+//
+// Recursive types, such as a struct with a field of its own type, are not supported and return an error.
+// A time.Time or a type implementing encoding.TextMarshaler is described as a string, and a type implementing
+// json.Marshaler as any value.
+//
+// The result is sent to the model as is if it is a string, and encoded as JSON otherwise.
+//
+// Parameters:
+//   - r: The registry.
+//   - name: The name of the tool.
+//   - description: What the tool does, used by the model to decide when to call it.
+//   - fn: The function called with the decoded arguments.
+func RegisterTool[T any, R any](r *ToolRegistry, name, description string, fn func(ctx context.Context, args T) (R, error)) error {
+	if len(name) == 0 {
+		return errors.New("register tool: empty name")
+	}
+
+	schema, err := schemaOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return fmt.Errorf("register tool %s: %w", name, err)
+	}
+
+	if schema.Type != "object" {
+		return fmt.Errorf("register tool %s: the arguments must be a struct, got %s", name, schema.Type)
+	}
+
+	tool := &registeredTool{
+		tool: NewFunctionTool(name, description, schema),
+	}
+	tool.call = func(ctx context.Context, args map[string]interface{}) (string, error) {
+		if args == nil {
+			args = make(map[string]interface{})
+		}
+
+		if errs := validateSchema(schema, args, ""); len(errs) != 0 {
+			return "", &ToolArgumentsError{Tool: name, Errors: errs}
+		}
+
+		data, err := json2.Marshal(args)
+		if err != nil {
+			return "", err
+		}
+
+		var v T
+		if err := json2.Unmarshal(data, &v); err != nil {
+			return "", &ToolArgumentsError{Tool: name, Errors: []ToolArgumentError{{Message: err.Error()}}}
+		}
+
+		res, err := fn(ctx, v)
+		if err != nil {
+			return "", err
+		}
+
+		if s, ok := interface{}(res).(string); ok {
+			return s, nil
+		}
+
+		data, err = json2.Marshal(res)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tools[name]; ok {
+		return fmt.Errorf("register tool %s: already registered", name)
+	}
+	r.tools[name] = tool
+	return nil
+}
+
+// Tools returns the registered tools sorted by name, to be sent with a chat request.
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]Tool, 0, len(r.tools))
+	for _, t := range r.tools {
+		res = append(res, t.tool)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Function.Name < res[j].Function.Name
+	})
+	return res
+}
+
+// Call invokes the tool requested by the model and returns the message with its result, to be sent back to the model.
+// If the tool is unknown, the arguments are invalid or the function fails, the message contains the error
+// as a JSON object, so the model can correct the call, and the error is also returned.
+//
+// Parameters:
+//   - ctx: The context passed to the function.
+//   - call: The tool call requested by the model.
+func (r *ToolRegistry) Call(ctx context.Context, call ToolCall) (Message, error) {
+	name := call.Function.Name
+
+	r.mu.RLock()
+	tool := r.tools[name]
+	r.mu.RUnlock()
+
+	var res string
+	var err error
+	if tool == nil {
+		err = fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	} else {
+		res, err = tool.call(ctx, call.Function.Arguments)
+	}
+
+	if err != nil {
+		return NewToolMessage(name, toolErrorContent(err)), err
+	}
+
+	return NewToolMessage(name, res), nil
+}
+
+// toolErrorContent encodes the error of a tool call as a JSON object for the model.
+func toolErrorContent(err error) string {
+	v := struct {
+		Error   string              `json:"error"`
+		Details []ToolArgumentError `json:"details,omitempty"`
+	}{
+		Error: err.Error(),
+	}
+
+	var argsErr *ToolArgumentsError
+	if errors.As(err, &argsErr) {
+		v.Error = "invalid arguments for tool " + argsErr.Tool
+		v.Details = argsErr.Errors
+	}
+
+	data, _ := json2.Marshal(v)
+	return string(data)
+}
+
+// ToolArgumentsError is returned when the arguments of a tool call do not match the schema of the tool.
+type ToolArgumentsError struct {
+	Tool   string              // The name of the tool.
+	Errors []ToolArgumentError // The validation failures.
+}
+
+func (e *ToolArgumentsError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.String())
+	}
+
+	return fmt.Sprintf("invalid arguments for tool %s: %s", e.Tool, strings.Join(msgs, "; "))
+}
+
+// ToolArgumentError is a validation failure of an argument of a tool call.
+type ToolArgumentError struct {
+	Path    string `json:"path,omitempty"` // The path of the argument, such as "address.city" or "items[2]". Empty for the whole arguments.
+	Message string `json:"message"`        // What is wrong with the argument.
+}
+
+func (e ToolArgumentError) String() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// The types whose JSON encoding is not derived from their fields.
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json2.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaOf derives the JSON schema of a Go type. Recursive types are not supported.
+func schemaOf(t reflect.Type) (*JSONSchema, error) {
+	return schemaOfType(t, make(map[reflect.Type]bool))
+}
+
+// schemaOfType derives the JSON schema of a Go type. visiting holds the struct types being derived,
+// so a type that contains itself is reported instead of being derived forever.
+func schemaOfType(t reflect.Type, visiting map[reflect.Type]bool) (*JSONSchema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Types with their own JSON encoding are described by it rather than by their fields
+	switch {
+	case t == timeType:
+		return &JSONSchema{Type: "string"}, nil // Encoded in RFC 3339 format
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &JSONSchema{}, nil // Any value, as the encoding is unknown
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &JSONSchema{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+	case reflect.Interface:
+		return &JSONSchema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string"}, nil // Encoded as base64
+		}
+
+		items, err := schemaOfType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		return &JSONSchema{Type: "object"}, nil
+	case reflect.Struct:
+		schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+		if err := addStructProperties(schema, t, visiting); err != nil {
+			return nil, err
+		}
+		return schema, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// addStructProperties adds the exported fields of the struct to the schema, including those of embedded structs.
+func addStructProperties(schema *JSONSchema, t reflect.Type, visiting map[reflect.Type]bool) error {
+	if visiting[t] {
+		return fmt.Errorf("recursive type %s is not supported", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && len(name) == 0 {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addStructProperties(schema, ft, visiting); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		prop, err := schemaOfType(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		prop.Description = field.Tag.Get("description")
+
+		if enum := field.Tag.Get("enum"); len(enum) != 0 {
+			for _, v := range strings.Split(enum, ",") {
+				value, err := enumValue(prop.Type, strings.TrimSpace(v))
+				if err != nil {
+					return fmt.Errorf("field %s: %w", field.Name, err)
+				}
+				prop.Enum = append(prop.Enum, value)
+			}
+		}
+
+		required := field.Type.Kind() != reflect.Pointer && !strings.Contains(","+opts+",", ",omitempty,")
+		if v := field.Tag.Get("required"); len(v) != 0 {
+			required = v == "true"
+		}
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = prop
+	}
+
+	return nil
+}
+
+// enumValue converts a value of the enum tag to the type of the property.
+func enumValue(typ, v string) (interface{}, error) {
+	switch typ {
+	case "integer":
+		return strconv.ParseInt(v, 10, 64)
+	case "number":
+		return strconv.ParseFloat(v, 64)
+	case "boolean":
+		return strconv.ParseBool(v)
+	case "string", "":
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("enum is not supported for type %s", typ)
+}
+
+// validateSchema checks a value decoded from JSON against the schema.
+func validateSchema(schema *JSONSchema, v interface{}, path string) []ToolArgumentError {
+	fail := func(format string, a ...interface{}) []ToolArgumentError {
+		return []ToolArgumentError{{Path: path, Message: fmt.Sprintf(format, a...)}}
+	}
+
+	if v == nil && (len(schema.Type) == 0 || len(path) != 0) {
+		return nil // Optional properties may be null
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fail("expected an object, got %s", jsonTypeOf(v))
+		}
+
+		var errs []ToolArgumentError
+		for _, name := range schema.Required {
+			if obj[name] == nil {
+				errs = append(errs, ToolArgumentError{Path: joinPath(path, name), Message: "is required"})
+			}
+		}
+
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if prop := schema.Properties[name]; prop != nil {
+				errs = append(errs, validateSchema(prop, obj[name], joinPath(path, name))...)
+			}
+		}
+		return errs
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fail("expected an array, got %s", jsonTypeOf(v))
+		}
+
+		var errs []ToolArgumentError
+		for i, item := range arr {
+			if schema.Items != nil {
+				errs = append(errs, validateSchema(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+		return errs
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fail("expected a string, got %s", jsonTypeOf(v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fail("expected a boolean, got %s", jsonTypeOf(v))
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fail("expected a number, got %s", jsonTypeOf(v))
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != math.Trunc(n) {
+			return fail("expected an integer, got %s", jsonTypeOf(v))
+		}
+	}
+
+	if len(schema.Enum) != 0 {
+		for _, e := range schema.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				return nil
+			}
+		}
+		return fail("must be one of %v", schema.Enum)
+	}
+
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonTypeOf returns the JSON type name of a value decoded from JSON.
+func jsonTypeOf(v interface{}) string {
+	switch n := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64:
+		if n == math.Trunc(n) {
+			return "an integer"
+		}
+		return "a number"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,225 @@
+package ollama
+
+import (
+	"context"
+	json2 "encoding/json"
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type weatherArgs struct {
+	City    string   `json:"city" description:"The name of the city"`
+	Unit    string   `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+	Days    *int     `json:"days" enum:"1,3,7"`
+	Tags    []string `json:"tags,omitempty"`
+	Verbose bool     `json:"verbose" required:"false"`
+	Ignored string   `json:"-"`
+	secret  string
+}
+
+type weatherReport struct {
+	City        string  `json:"city"`
+	Temperature float64 `json:"temperature"`
+}
+
+func newWeatherRegistry(t *testing.T) *ToolRegistry {
+	tools := NewToolRegistry()
+	err := RegisterTool(tools, "get_weather", "Get the current weather of a city", func(ctx context.Context, args weatherArgs) (weatherReport, error) {
+		if args.City == "Atlantis" {
+			return weatherReport{}, errors.New("city not found")
+		}
+		return weatherReport{City: args.City, Temperature: 21.5}, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterTool returned an error: %s", err)
+	}
+	return tools
+}
+
+func TestToolRegistrySchema(t *testing.T) {
+	tools := newWeatherRegistry(t).Tools()
+	if len(tools) != 1 || tools[0].Type != "function" || tools[0].Function.Name != "get_weather" {
+		t.Fatalf("Unexpected tools: %+v", tools)
+	}
+
+	schema := tools[0].Function.Parameters
+	if !reflect.DeepEqual(schema.Required, []string{"city"}) {
+		t.Errorf("Expected only city to be required, got %v", schema.Required)
+	}
+
+	if len(schema.Properties) != 5 {
+		t.Errorf("Expected 5 properties, got %d", len(schema.Properties))
+	}
+
+	city, unit, days, tags := schema.Properties["city"], schema.Properties["unit"], schema.Properties["days"], schema.Properties["tags"]
+	if city.Type != "string" || city.Description != "The name of the city" {
+		t.Errorf("Unexpected city schema: %+v", city)
+	}
+	if !reflect.DeepEqual(unit.Enum, []interface{}{"celsius", "fahrenheit"}) {
+		t.Errorf("Unexpected unit enum: %v", unit.Enum)
+	}
+	if days.Type != "integer" || !reflect.DeepEqual(days.Enum, []interface{}{int64(1), int64(3), int64(7)}) {
+		t.Errorf("Unexpected days schema: %+v", days)
+	}
+	if tags.Type != "array" || tags.Items.Type != "string" {
+		t.Errorf("Unexpected tags schema: %+v", tags)
+	}
+
+	if err := RegisterTool(NewToolRegistry(), "bad", "", func(ctx context.Context, args string) (string, error) { return args, nil }); err == nil {
+		t.Errorf("Expected an error for arguments that are not a struct")
+	}
+}
+
+type treeNode struct {
+	Name     string     `json:"name"`
+	Children []treeNode `json:"children"`
+}
+
+type linkedNode struct {
+	Value int         `json:"value"`
+	Next  *linkedNode `json:"next"`
+}
+
+// EmbeddedNode is exported, as the fields of an embedded pointer to an unexported struct are ignored
+type EmbeddedNode struct {
+	Name string `json:"name"`
+	*EmbeddedNode
+}
+
+type mutualA struct {
+	B *mutualB `json:"b"`
+}
+
+type mutualB struct {
+	A []mutualA `json:"a"`
+}
+
+type reusedArgs struct {
+	From weatherReport `json:"from"`
+	To   weatherReport `json:"to"`
+}
+
+func TestSchemaOfRecursiveTypes(t *testing.T) {
+	tests := map[string]func() (*JSONSchema, error){
+		"slice":    SchemaOf[treeNode],
+		"pointer":  SchemaOf[linkedNode],
+		"embedded": SchemaOf[EmbeddedNode],
+		"mutual":   SchemaOf[mutualA],
+	}
+
+	for name, schemaOf := range tests {
+		t.Run(name, func(t *testing.T) {
+			if schema, err := schemaOf(); err == nil {
+				t.Errorf("Expected an error for a recursive type, got %+v", schema)
+			}
+		})
+	}
+
+	err := RegisterTool(NewToolRegistry(), "tree", "", func(ctx context.Context, args treeNode) (string, error) { return args.Name, nil })
+	if err == nil {
+		t.Errorf("Expected RegisterTool to fail for a recursive type")
+	}
+
+	// A type used by several fields is not recursive
+	schema, err := SchemaOf[reusedArgs]()
+	if err != nil {
+		t.Fatalf("SchemaOf returned an error: %s", err)
+	}
+	if schema.Properties["from"].Properties["city"] == nil || schema.Properties["to"].Properties["city"] == nil {
+		t.Errorf("Unexpected schema: %+v", schema)
+	}
+}
+
+type meetingArgs struct {
+	When  time.Time        `json:"when"`
+	Until *time.Time       `json:"until,omitempty"`
+	Host  netip.Addr       `json:"host"`
+	Extra json2.RawMessage `json:"extra,omitempty"`
+}
+
+func TestSchemaOfMarshalers(t *testing.T) {
+	schema, err := SchemaOf[meetingArgs]()
+	if err != nil {
+		t.Fatalf("SchemaOf returned an error: %s", err)
+	}
+
+	for name, expected := range map[string]string{"when": "string", "until": "string", "host": "string", "extra": ""} {
+		if prop := schema.Properties[name]; prop == nil || prop.Type != expected || prop.Properties != nil {
+			t.Errorf("Expected the property %s to have the type %q, got %+v", name, expected, prop)
+		}
+	}
+
+	tools := NewToolRegistry()
+	err = RegisterTool(tools, "schedule", "Schedule a meeting", func(ctx context.Context, args meetingArgs) (string, error) {
+		return args.When.UTC().Format(time.RFC3339) + " " + args.Host.String() + " " + string(args.Extra), nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterTool returned an error: %s", err)
+	}
+
+	args := map[string]interface{}{"when": "2024-05-01T10:00:00+02:00", "host": "10.0.0.1", "extra": map[string]interface{}{"room": 4}}
+	m, err := tools.Call(context.Background(), ToolCall{Function: ToolCallFunction{Name: "schedule", Arguments: args}})
+	if err != nil {
+		t.Fatalf("Call returned an error: %s", err)
+	}
+	if *m.Content != `2024-05-01T08:00:00Z 10.0.0.1 {"room":4}` {
+		t.Errorf("Unexpected result: %s", *m.Content)
+	}
+}
+
+func TestToolRegistryCall(t *testing.T) {
+	tools := newWeatherRegistry(t)
+	ctx := context.Background()
+
+	call := func(name string, args string) (Message, error) {
+		var v map[string]interface{}
+		if err := json2.Unmarshal([]byte(args), &v); err != nil {
+			t.Fatalf("invalid arguments: %s", err)
+		}
+		return tools.Call(ctx, ToolCall{Function: ToolCallFunction{Name: name, Arguments: v}})
+	}
+
+	m, err := call("get_weather", `{"city":"Paris","days":3}`)
+	if err != nil {
+		t.Fatalf("Call returned an error: %s", err)
+	}
+	if *m.Role != "tool" || *m.ToolName != "get_weather" || *m.Content != `{"city":"Paris","temperature":21.5}` {
+		t.Errorf("Unexpected result message: %s", *m.Content)
+	}
+
+	m, err = call("get_weather", `{"unit":"kelvin","days":2.5,"tags":["a",1]}`)
+	var argsErr *ToolArgumentsError
+	if !errors.As(err, &argsErr) {
+		t.Fatalf("Expected a ToolArgumentsError, got %v", err)
+	}
+
+	expected := []ToolArgumentError{
+		{Path: "city", Message: "is required"},
+		{Path: "days", Message: "expected an integer, got a number"},
+		{Path: "tags[1]", Message: "expected a string, got an integer"},
+		{Path: "unit", Message: "must be one of [celsius fahrenheit]"},
+	}
+	if !reflect.DeepEqual(argsErr.Errors, expected) {
+		t.Errorf("Unexpected validation errors: %+v", argsErr.Errors)
+	}
+
+	var content struct {
+		Error   string              `json:"error"`
+		Details []ToolArgumentError `json:"details"`
+	}
+	if err := json2.Unmarshal([]byte(*m.Content), &content); err != nil || len(content.Details) != 4 {
+		t.Errorf("Expected the validation errors to be sent to the model, got %s", *m.Content)
+	}
+
+	m, err = call("get_weather", `{"city":"Atlantis"}`)
+	if err == nil || *m.Content != `{"error":"city not found"}` {
+		t.Errorf("Expected the error of the function to be sent to the model, got %s", *m.Content)
+	}
+
+	if _, err = call("get_time", `{}`); !errors.Is(err, ErrUnknownTool) {
+		t.Errorf("Expected ErrUnknownTool, got %v", err)
+	}
+}
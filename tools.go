@@ -0,0 +1,66 @@
+package ollama
+
+// Tool represents a tool that the model may call during a chat.
+type Tool struct {
+	Type     string       `json:"type"` // The type of the tool. Currently, the only accepted value is "function".
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a function that the model may call.
+type ToolFunction struct {
+	Name        string      `json:"name"`                  // The name of the function.
+	Description string      `json:"description,omitempty"` // What the function does, used by the model to decide when to call it.
+	Parameters  *JSONSchema `json:"parameters,omitempty"`  // The JSON schema of the arguments of the function.
+}
+
+// JSONSchema is the subset of JSON schema used to describe the arguments of tools.
+type JSONSchema struct {
+	Type        string                 `json:"type,omitempty"` // Either object, array, string, number, integer, or boolean.
+	Description string                 `json:"description,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"` // The properties of an object.
+	Required    []string               `json:"required,omitempty"`   // The required properties of an object.
+	Items       *JSONSchema            `json:"items,omitempty"`      // The schema of the items of an array.
+	Enum        []interface{}          `json:"enum,omitempty"`       // The allowed values.
+}
+
+// ToolCall represents a call of a tool requested by the model.
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction is the function called by a ToolCall.
+type ToolCallFunction struct {
+	Index     int                    `json:"index,omitempty"` // The index of the call in the reply.
+	Name      string                 `json:"name"`            // The name of the function.
+	Arguments map[string]interface{} `json:"arguments"`       // The arguments of the call, as decoded from JSON.
+}
+
+// NewFunctionTool creates a function tool.
+//
+// Parameters:
+//   - name: The name of the function.
+//   - description: What the function does.
+//   - parameters: The JSON schema of the arguments, usually of type object.
+func NewFunctionTool(name, description string, parameters *JSONSchema) Tool {
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+	}
+}
+
+// NewToolMessage creates a message with the result of a tool call, to be sent back to the model.
+//
+// Parameters:
+//   - name: The name of the called function.
+//   - content: The result of the call.
+func NewToolMessage(name, content string) Message {
+	return Message{
+		Role:     pointer("tool"),
+		Content:  &content,
+		ToolName: &name,
+	}
+}
@@ -0,0 +1,66 @@
+package ollama
+
+import (
+	json2 "encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestChatToolCalls(t *testing.T) {
+	var tools []Tool
+	var messages []Message
+
+	llm := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequestBuilder
+		if err := json2.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		tools, messages = req.Tools, req.Messages
+
+		if n := len(req.Messages); n > 0 && *req.Messages[n-1].Role == "tool" {
+			w.Write([]byte(`{"message":{"role":"assistant","content":"It is sunny."},"done":true}` + "\n"))
+			return
+		}
+
+		// The calls are streamed in separate chunks
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Paris"}}}]},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"index":1,"name":"get_weather","arguments":{"city":"Rome"}}}]},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}` + "\n"))
+	})
+
+	weather := NewFunctionTool("get_weather", "Get the current weather of a city", &JSONSchema{
+		Type:       "object",
+		Properties: map[string]*JSONSchema{"city": {Type: "string"}},
+		Required:   []string{"city"},
+	})
+
+	chatId := "tools"
+	res, err := llm.Chat(&chatId, llm.Chat.WithModel("llama3"), llm.Chat.WithTools(weather), llm.Chat.WithStream(true, 512, nil))
+	if err != nil {
+		t.Fatalf("Chat returned an error: %s", err)
+	}
+
+	if len(tools) != 1 || tools[0].Function.Name != "get_weather" || tools[0].Function.Parameters.Required[0] != "city" {
+		t.Errorf("Expected the tools to be sent, got %+v", tools)
+	}
+
+	calls := res.Message.ToolCalls
+	if len(calls) != 2 || calls[0].Function.Arguments["city"] != "Paris" || calls[1].Function.Arguments["city"] != "Rome" || calls[1].Function.Index != 1 {
+		t.Fatalf("Expected the tool calls of all chunks, got %+v", calls)
+	}
+
+	_, err = llm.Chat(&chatId, llm.Chat.WithModel("llama3"), llm.Chat.WithToolResult("get_weather", "sunny"), llm.Chat.WithToolResult("get_weather", "sunny"))
+	if err != nil {
+		t.Fatalf("Chat returned an error: %s", err)
+	}
+
+	if len(messages) != 3 || len(messages[0].ToolCalls) != 2 || *messages[1].ToolName != "get_weather" {
+		t.Errorf("Expected the tool calls and results to be sent with the history, got %+v", messages)
+	}
+
+	history := mustGetChat(t, llm, chatId).Messages
+	if len(history) != 4 || len(history[0].ToolCalls) != 2 || *history[2].Role != "tool" || *history[3].Content != "It is sunny." {
+		t.Errorf("Expected the tool calls and results to be stored, got %+v", history)
+	}
+}
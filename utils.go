@@ -14,6 +14,33 @@ func bodyTo[T any](body []byte) (*T, error) {
 	return &response, nil
 }
 
+// streamFuncOf adapts a typed stream handler, or stream function if the handler is nil,
+// to the raw stream function accepted by Ollama.stream.
+func streamFuncOf[T any](fn func(r *T, err error), handler func(r *T, err error) error) func(b []byte, err error) error {
+	if handler == nil {
+		if fn == nil {
+			return nil
+		}
+
+		handler = func(r *T, err error) error {
+			fn(r, err)
+			return nil
+		}
+	}
+
+	return func(b []byte, err error) error {
+		if err != nil {
+			return handler(nil, err)
+		}
+
+		r, err := bodyTo[T](b)
+		if err != nil {
+			return handler(nil, err)
+		}
+		return handler(r, nil)
+	}
+}
+
 func pointer[T any](t T) *T {
 	return &t
 }
@@ -30,36 +57,3 @@ func buildUrl(baseUrl, path string) string {
 	url += path
 	return url
 }
-
-func splitJSONObjects(data []byte) [][]byte {
-	var results [][]byte
-	var stack []byte
-	var start, end int
-	var inString bool
-
-	for i := 0; i < len(data); i++ {
-		switch data[i] {
-		case '{':
-			if !inString {
-				if len(stack) == 0 {
-					start = i
-				}
-				stack = append(stack, '{')
-			}
-		case '}':
-			if !inString {
-				stack = stack[:len(stack)-1]
-				if len(stack) == 0 {
-					end = i + 1
-					results = append(results, data[start:end])
-				}
-			}
-		case '"':
-			if i == 0 || data[i-1] != '\\' {
-				inString = !inString
-			}
-		}
-	}
-
-	return results
-}